@@ -0,0 +1,60 @@
+package blueberry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// ErrRunAbandoned is the error seen by retry policies for runs abandoned when the process stopped
+var ErrRunAbandoned = errors.New("run abandoned")
+
+// markAbandonedRuns ends the runs still stored as "started" that this process is not executing and returns them.
+// They were running when the previous process crashed or was killed. The store is assumed to belong to this
+// instance only.
+func (r *BlueBerry) markAbandonedRuns() []*TaskRun {
+	taskRuns, err := r.db.GetTaskRunsByStatus(context.Background(), "started")
+	if err != nil {
+		log.Errorf("unable to load started runs: %v", err)
+		return nil
+	}
+
+	var abandoned []*TaskRun
+	for i := range taskRuns {
+		taskRun := &taskRuns[i]
+		if _, ok := r.executing.Load(taskRun.ID); ok {
+			continue
+		}
+
+		taskRun.Status = "abandoned"
+		taskRun.EndTime = time.Now().UTC()
+		if err := r.db.SaveTaskRun(context.Background(), taskRun); err != nil {
+			log.Errorf("unable to save abandoned run %d: %v", taskRun.ID, err)
+			continue
+		}
+		logger := &Logger{taskRun: taskRun, db: r.db}
+		_ = logger.Error("Run abandoned: the process stopped while the task was running")
+		abandoned = append(abandoned, taskRun)
+	}
+	return abandoned
+}
+
+// retryAbandonedRuns retries the abandoned runs of tasks whose retry policy opts in. It runs after the pending
+// runs are restored, so that retries starting right away are not restored a second time.
+func (r *BlueBerry) retryAbandonedRuns(abandoned []*TaskRun) {
+	for _, taskRun := range abandoned {
+		task, ok := r.GetTask(taskRun.TaskName)
+		if !ok || !task.options.Retry.RetryAbandoned {
+			continue
+		}
+
+		logger := &Logger{taskRun: taskRun, db: r.db}
+		if err := task.ValidateParams(taskRun.Params); err != nil {
+			_ = logger.Error("Unable to retry the abandoned run due to: " + err.Error())
+			continue
+		}
+		task.retry(taskRun, restoredRunOptions(taskRun), ErrRunAbandoned, logger)
+	}
+}
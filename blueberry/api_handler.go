@@ -2,9 +2,11 @@ package blueberry
 
 import (
 	"context"
+	"errors"
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // apiKeyAuthMiddleware checks the API key for API authentication
@@ -44,6 +46,270 @@ func (r *BlueBerry) getTasks(c echo.Context) error {
 	return c.JSON(http.StatusOK, tasks)
 }
 
+// getTaskSchedule returns a single schedule of a task by its schedule ID
+// @Summary Get a schedule by ID
+// @Description Get a schedule of a task by its stable schedule ID
+// @Param name path string true "Task Name"
+// @Param id path int true "Schedule ID"
+// @Tags Schedules
+// @Produce json
+// @Success 200 {object} ScheduleInfo
+// @Failure 400 {object} ErrorResponse "Invalid schedule ID"
+// @Failure 404 {object} ErrorResponse "Task or schedule not found"
+// @Router /task/{name}/schedules/{id} [get]
+func (r *BlueBerry) getTaskSchedule(c echo.Context) error {
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			"user",
+			"Invalid task name",
+		})
+	}
+
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			"Invalid schedule ID",
+		})
+	}
+
+	schedule, err := task.GetSchedule(scheduleID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			"user",
+			err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// createTaskSchedule registers a new schedule for a task
+// @Summary Create a schedule for a task
+// @Description Register a new schedule for a task with the given parameters and cron expression
+// @Accept json
+// @Produce json
+// @Param name path string true "Task Name"
+// @Param schedule body ScheduleRequest true "Schedule"
+// @Tags Schedules
+// @Success 201 {object} ScheduleInfo
+// @Failure 400 {object} ErrorResponse "Invalid parameters or cron expression"
+// @Failure 404 {object} ErrorResponse "Task not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /task/{name}/schedules [post]
+// @Security ApiKeyAuth
+func (r *BlueBerry) createTaskSchedule(c echo.Context) error {
+	var req ScheduleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			err.Error(),
+		})
+	}
+
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			"user",
+			"Invalid task name",
+		})
+	}
+
+	if err := task.ValidateParams(req.Params); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			err.Error(),
+		})
+	}
+
+	schedule, err := task.CreateScheduleWithOptions(req.Params, req.Schedule, req.ScheduleOptions)
+	if err != nil {
+		return scheduleErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, schedule)
+}
+
+// updateTaskSchedule replaces the parameters and cron expression of a schedule
+// @Summary Update a schedule of a task
+// @Description Replace the parameters, cron expression and options of a schedule, keeping its schedule ID
+// @Accept json
+// @Produce json
+// @Param name path string true "Task Name"
+// @Param id path int true "Schedule ID"
+// @Param schedule body ScheduleRequest true "Schedule"
+// @Tags Schedules
+// @Success 200 {object} ScheduleInfo
+// @Failure 400 {object} ErrorResponse "Invalid parameters or cron expression"
+// @Failure 404 {object} ErrorResponse "Task or schedule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /task/{name}/schedules/{id} [put]
+// @Security ApiKeyAuth
+func (r *BlueBerry) updateTaskSchedule(c echo.Context) error {
+	var req ScheduleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			err.Error(),
+		})
+	}
+
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			"user",
+			"Invalid task name",
+		})
+	}
+
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			"Invalid schedule ID",
+		})
+	}
+
+	if err := task.ValidateParams(req.Params); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			err.Error(),
+		})
+	}
+
+	schedule, err := task.UpdateScheduleWithOptions(scheduleID, req.Params, req.Schedule, req.ScheduleOptions)
+	if err != nil {
+		return scheduleErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// deleteTaskSchedule removes a schedule of a task
+// @Summary Delete a schedule of a task
+// @Description Remove a schedule of a task by its schedule ID
+// @Produce json
+// @Param name path string true "Task Name"
+// @Param id path int true "Schedule ID"
+// @Tags Schedules
+// @Success 200 {object} GenericResponse "Schedule deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid schedule ID"
+// @Failure 404 {object} ErrorResponse "Task or schedule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /task/{name}/schedules/{id} [delete]
+// @Security ApiKeyAuth
+func (r *BlueBerry) deleteTaskSchedule(c echo.Context) error {
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			"user",
+			"Invalid task name",
+		})
+	}
+
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			"Invalid schedule ID",
+		})
+	}
+
+	if err := task.DeleteSchedule(scheduleID); err != nil {
+		return scheduleErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, GenericResponse{
+		"message": "Schedule deleted successfully",
+	})
+}
+
+// pauseTaskSchedule pauses a schedule of a task
+// @Summary Pause a schedule of a task
+// @Description Stop a schedule from triggering while keeping its parameters and cron expression
+// @Produce json
+// @Param name path string true "Task Name"
+// @Param id path int true "Schedule ID"
+// @Tags Schedules
+// @Success 200 {object} ScheduleInfo
+// @Failure 400 {object} ErrorResponse "Invalid schedule ID"
+// @Failure 404 {object} ErrorResponse "Task or schedule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /task/{name}/schedules/{id}/pause [post]
+// @Security ApiKeyAuth
+func (r *BlueBerry) pauseTaskSchedule(c echo.Context) error {
+	return r.setTaskSchedulePaused(c, true)
+}
+
+// resumeTaskSchedule resumes a paused schedule of a task
+// @Summary Resume a schedule of a task
+// @Description Resume triggering a paused schedule
+// @Produce json
+// @Param name path string true "Task Name"
+// @Param id path int true "Schedule ID"
+// @Tags Schedules
+// @Success 200 {object} ScheduleInfo
+// @Failure 400 {object} ErrorResponse "Invalid schedule ID"
+// @Failure 404 {object} ErrorResponse "Task or schedule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /task/{name}/schedules/{id}/resume [post]
+// @Security ApiKeyAuth
+func (r *BlueBerry) resumeTaskSchedule(c echo.Context) error {
+	return r.setTaskSchedulePaused(c, false)
+}
+
+func (r *BlueBerry) setTaskSchedulePaused(c echo.Context, paused bool) error {
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			"user",
+			"Invalid task name",
+		})
+	}
+
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			"Invalid schedule ID",
+		})
+	}
+
+	var schedule ScheduleInfo
+	if paused {
+		schedule, err = task.PauseSchedule(scheduleID)
+	} else {
+		schedule, err = task.ResumeSchedule(scheduleID)
+	}
+	if err != nil {
+		return scheduleErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// scheduleErrorResponse maps errors returned by the schedule operations to API responses
+func scheduleErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrScheduleNotFound):
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			"user",
+			err.Error(),
+		})
+	case errors.Is(err, ErrInvalidSchedule):
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			"system",
+			err.Error(),
+		})
+	}
+}
+
 // getTaskExecutions returns all executions for a specific task
 // @Summary Get all executions for a specific task
 // @Description Get all executions for a specific task by name
@@ -62,25 +328,7 @@ func (r *BlueBerry) getTaskExecutions(c echo.Context) error {
 	var taskExecutions []TaskExecution
 	for _, taskRun := range taskRuns {
 		if taskRun.TaskName == taskName {
-			var duration string
-			var status string
-			if taskRun.EndTime.IsZero() {
-				duration = "ongoing"
-				status = "ongoing"
-			} else {
-				duration = taskRun.EndTime.Sub(taskRun.StartTime).String()
-				status = taskRun.Status
-			}
-
-			taskExecutions = append(taskExecutions, TaskExecution{
-				ID:        taskRun.ID,
-				TaskName:  taskRun.TaskName,
-				StartTime: taskRun.StartTime,
-				EndTime:   taskRun.EndTime,
-				Duration:  duration,
-				Params:    taskRun.Params,
-				Status:    status,
-			})
+			taskExecutions = append(taskExecutions, newTaskExecution(taskRun))
 		}
 	}
 
@@ -89,6 +337,73 @@ func (r *BlueBerry) getTaskExecutions(c echo.Context) error {
 	})
 }
 
+// getExecution returns a task execution by its ID
+// @Summary Get a task execution by ID
+// @Description Get the status, parameters and result of a task execution
+// @Param id path int true "Task Execution ID"
+// @Tags Executions
+// @Produce json
+// @Success 200 {object} TaskExecution
+// @Failure 400 {object} ErrorResponse "Invalid execution ID"
+// @Failure 404 {object} ErrorResponse "Execution not found"
+// @Router /execution/{id} [get]
+func (r *BlueBerry) getExecution(c echo.Context) error {
+	executionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			"Invalid execution ID",
+		})
+	}
+
+	taskRun, err := r.db.GetTaskRunByID(context.Background(), executionID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			"user",
+			"Execution not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, newTaskExecution(*taskRun))
+}
+
+// newTaskExecution converts a stored run into its API representation
+func newTaskExecution(taskRun TaskRun) TaskExecution {
+	var duration string
+	var status string
+	if taskRun.Status == "pending" || taskRun.Status == "queued" {
+		duration = taskRun.Status
+		status = taskRun.Status
+	} else if taskRun.EndTime.IsZero() {
+		duration = "ongoing"
+		status = "ongoing"
+	} else {
+		duration = taskRun.EndTime.Sub(taskRun.StartTime).String()
+		status = taskRun.Status
+	}
+
+	return TaskExecution{
+		ID:        taskRun.ID,
+		TaskName:  taskRun.TaskName,
+		StartTime: taskRun.StartTime,
+		EndTime:   taskRun.EndTime,
+		Duration:  duration,
+		Params:    taskRun.Params,
+		Status:    status,
+		CatchUp:   taskRun.CatchUp,
+		RunAt:     taskRun.RunAt,
+		Attempt:   taskRun.Attempt,
+		RetryOf:   taskRun.RetryOf,
+		Priority:  taskRun.Priority,
+
+		ScheduleID: taskRun.ScheduleID,
+		Timeout:    taskRun.Timeout,
+
+		IdempotencyKey: taskRun.IdempotencyKey,
+		Result:         taskRun.Result,
+	}
+}
+
 // getTaskRunLogs returns all logs for a specific task run
 // @Summary Get all logs for a specific task run
 // @Description Get all logs for a specific task run by ID with pagination and log level filtering
@@ -134,7 +449,7 @@ func (r *BlueBerry) getTaskRunLogs(c echo.Context) error {
 
 // CancelExecutionByID cancels a specific task execution by ID
 // @Summary Cancel a specific task execution by ID
-// @Description Cancel a specific task execution by its ID
+// @Description Cancel a running, pending or queued task execution by its ID
 // @Param id path int true "Task Execution ID"
 // @Tags Executions
 // @Produce json
@@ -159,14 +474,17 @@ func (r *BlueBerry) cancelExecutionByID(c echo.Context) error {
 
 // executeTaskByName handles the execution of a task by its name
 // @Summary Execute a task by name
-// @Description Execute a specified task by its name with the provided parameters
+// @Description Execute a specified task by its name with the provided parameters.
+// @Description Repeated requests with the same idempotency key return the execution ID of the first one.
 // @Accept json
 // @Produce json
 // @Param name path string true "Task Name"
+// @Param Idempotency-Key header string false "Idempotency key of at most 255 characters, alternative to the idempotency_key field"
 // @Param params body ExecuteTaskRequest true "Task Parameters"
 // @Success 200 {object} GenericResponse "Task executed successfully"
 // @Failure 400 {object} ErrorResponse "Invalid parameters"
 // @Failure 404 {object} ErrorResponse "Task not found"
+// @Failure 409 {object} ErrorResponse "A run with the same parameters is already queued or running"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /task/{name}/execute [post]
 // @Security ApiKeyAuth
@@ -196,8 +514,40 @@ func (r *BlueBerry) executeTaskByName(c echo.Context) error {
 			err.Error(),
 		})
 	}
+	if req.Timeout < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			"Timeout must not be negative",
+		})
+	}
+
+	idempotencyKey := strings.TrimSpace(c.Request().Header.Get("Idempotency-Key"))
+	if idempotencyKey == "" {
+		idempotencyKey = req.IdempotencyKey
+	} else if req.IdempotencyKey != "" && req.IdempotencyKey != idempotencyKey {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			"Idempotency-Key header and idempotency_key field differ",
+		})
+	}
+	if err := validateIdempotencyKey(idempotencyKey); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			err.Error(),
+		})
+	}
 
-	taskID, err := task.ExecuteNow(req.Params)
+	taskID, err := task.ExecuteNowWithOptions(req.Params, ExecuteOptions{
+		Timeout:        req.Timeout,
+		Priority:       req.Priority,
+		IdempotencyKey: idempotencyKey,
+	})
+	if errors.Is(err, ErrAlreadyRunning) {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			"user",
+			err.Error(),
+		})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			"system",
@@ -209,3 +559,90 @@ func (r *BlueBerry) executeTaskByName(c echo.Context) error {
 		"execution_id": taskID,
 	})
 }
+
+// getMaintenanceMode returns whether the global maintenance mode is enabled
+// @Summary Get maintenance mode
+// @Description While maintenance mode is enabled scheduled triggers are skipped and recorded as skipped runs
+// @Tags Maintenance
+// @Produce json
+// @Success 200 {object} MaintenanceMode
+// @Router /maintenance [get]
+func (r *BlueBerry) getMaintenanceMode(c echo.Context) error {
+	return c.JSON(http.StatusOK, MaintenanceMode{Enabled: r.IsMaintenanceMode()})
+}
+
+// setMaintenanceMode enables or disables the global maintenance mode
+// @Summary Set maintenance mode
+// @Description Suspend or restore all scheduled triggers. Manual executions are still allowed.
+// @Tags Maintenance
+// @Accept json
+// @Produce json
+// @Param request body MaintenanceMode true "Maintenance mode"
+// @Success 200 {object} MaintenanceMode
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Router /maintenance [put]
+// @Security ApiKeyAuth
+func (r *BlueBerry) setMaintenanceMode(c echo.Context) error {
+	var req MaintenanceMode
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			"validation",
+			"Invalid request payload",
+		})
+	}
+
+	r.SetMaintenanceMode(req.Enabled)
+	return c.JSON(http.StatusOK, MaintenanceMode{Enabled: r.IsMaintenanceMode()})
+}
+
+// getQueue returns the runs waiting for a slot
+// @Summary Get the run queue
+// @Description Get the queued runs in the order they start in, runs with a higher priority first
+// @Tags Executions
+// @Produce json
+// @Success 200 {array} QueuedRun
+// @Router /queue [get]
+func (r *BlueBerry) getQueue(c echo.Context) error {
+	return c.JSON(http.StatusOK, r.GetQueue())
+}
+
+// getCalendars returns the registered calendars that schedules can be attached to
+// @Summary Get calendars
+// @Description Get all registered calendars with their excluded dates, weekdays and blackout windows
+// @Tags Calendars
+// @Produce json
+// @Success 200 {array} Calendar
+// @Router /calendars [get]
+func (r *BlueBerry) getCalendars(c echo.Context) error {
+	return c.JSON(http.StatusOK, r.listCalendars())
+}
+
+// previewSchedule returns the upcoming firing times of a cron expression without registering it
+// @Summary Preview a cron expression
+// @Description Get the next firing times and a human-readable description of a cron expression
+// @Tags Schedules
+// @Produce json
+// @Param schedule query string true "Cron expression"
+// @Param timezone query string false "IANA time zone, the server default when empty"
+// @Param count query int false "Number of firing times (1-100, default 5)"
+// @Success 200 {object} SchedulePreview
+// @Failure 400 {object} ErrorResponse "Invalid cron expression, time zone or count"
+// @Router /schedule/preview [get]
+func (r *BlueBerry) previewSchedule(c echo.Context) error {
+	count := 5
+	if value := c.QueryParam("count"); value != "" {
+		var err error
+		if count, err = strconv.Atoi(value); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				"validation",
+				"Invalid count",
+			})
+		}
+	}
+
+	preview, err := r.PreviewSchedule(c.QueryParam("schedule"), count, c.QueryParam("timezone"))
+	if err != nil {
+		return scheduleErrorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, preview)
+}
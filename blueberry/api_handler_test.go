@@ -0,0 +1,183 @@
+package blueberry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// callAPI calls an API handler with a JSON body, params holds the names and values of the path parameters
+func callAPI(t *testing.T, handler echo.HandlerFunc, method, body string, params ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names, values = append(names, params[i]), append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestScheduleAPIOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		check      func(t *testing.T, schedule ScheduleInfo)
+	}{
+		{"overlap policy", `{"overlap": "queue"}`, http.StatusCreated, func(t *testing.T, schedule ScheduleInfo) {
+			if schedule.Overlap != OverlapQueue {
+				t.Errorf("overlap = %q, want queue", schedule.Overlap)
+			}
+		}},
+		{"unknown overlap policy", `{"overlap": "sometimes"}`, http.StatusBadRequest, nil},
+		{"misfire policy with a limit", `{"misfire": "fire_all", "misfire_limit": 5}`, http.StatusCreated, func(t *testing.T, schedule ScheduleInfo) {
+			if schedule.Misfire != MisfireFireAll || schedule.MisfireLimit != 5 {
+				t.Errorf("misfire = %q with limit %d, want fire_all with limit 5", schedule.Misfire, schedule.MisfireLimit)
+			}
+		}},
+		{"fire all without a limit", `{"misfire": "fire_all"}`, http.StatusBadRequest, nil},
+		{"unknown misfire policy", `{"misfire": "sometimes"}`, http.StatusBadRequest, nil},
+		{"spread moves the next execution", `{"spread": 3600000000000}`, http.StatusCreated, func(t *testing.T, schedule ScheduleInfo) {
+			due := time.Date(time.Now().UTC().Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+			delay := (&BlueBerry{}).triggerDelay(schedule, due)
+			if delay == 0 || schedule.NextExecution != due.Add(delay).Unix() {
+				t.Errorf("next execution = %d, want %d (spread by %s)", schedule.NextExecution, due.Add(delay).Unix(), delay)
+			}
+		}},
+		{"negative jitter", `{"jitter": -1}`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := newTestInstance(t, newMemoryDB(), Options{Location: time.UTC})
+			if _, err := rb.RegisterTask("report", (&runCounter{}).task, versionSchema); err != nil {
+				t.Fatal(err)
+			}
+
+			// The options are merged into the request next to the parameters and the cron expression
+			body := `{"params": {"version": "v1"}, "schedule": "0 0 1 1 *", ` + strings.TrimPrefix(tt.body, "{")
+			rec := callAPI(t, rb.createTaskSchedule, http.MethodPost, body, "name", "report")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body, tt.wantStatus)
+			}
+			if tt.check == nil {
+				return
+			}
+			var schedule ScheduleInfo
+			if err := json.Unmarshal(rec.Body.Bytes(), &schedule); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, schedule)
+		})
+	}
+}
+
+func TestQueueAPIListsQueuedTriggers(t *testing.T) {
+	rb := newTestInstance(t, newMemoryDB(), Options{})
+	release := make(chan struct{})
+	defer close(release)
+	task, _ := rb.RegisterTask("report", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}, versionSchema)
+	scheduleInfo, err := task.CreateScheduleWithOptions(TaskParams{"version": "v1"}, "0 0 1 1 *", ScheduleOptions{Overlap: OverlapQueue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task.fireSchedule(scheduleInfo, time.Now(), false)
+	task.fireSchedule(scheduleInfo, time.Now(), false)
+
+	rec := callAPI(t, rb.getQueue, http.MethodGet, "")
+	var queue []QueuedRun
+	if err := json.Unmarshal(rec.Body.Bytes(), &queue); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].ScheduleID != scheduleInfo.ID {
+		t.Fatalf("queue = %+v, want the second trigger of schedule %d", queue, scheduleInfo.ID)
+	}
+
+	rec = callAPI(t, rb.getExecution, http.MethodGet, "", "id", strconv.Itoa(queue[0].ID))
+	var execution TaskExecution
+	if err := json.Unmarshal(rec.Body.Bytes(), &execution); err != nil {
+		t.Fatal(err)
+	}
+	if execution.Status != "queued" || execution.ScheduleID != scheduleInfo.ID {
+		t.Errorf("queued trigger has status %q and schedule %d, want queued and %d", execution.Status, execution.ScheduleID, scheduleInfo.ID)
+	}
+}
+
+func TestGetExecutionAPI(t *testing.T) {
+	tests := []struct {
+		name       string
+		taskFunc   ResultTaskFunc
+		wantStatus string
+		wantResult string
+	}{
+		{"result of a completed run", func(context.Context, TaskParams, *Logger) (any, error) {
+			return map[string]int{"rows": 42}, nil
+		}, "completed", `{"rows":42}`},
+		{"failed run without result", func(context.Context, TaskParams, *Logger) (any, error) {
+			return nil, errors.New("no rows")
+		}, "failed", ""},
+		{"panicked run", func(context.Context, TaskParams, *Logger) (any, error) {
+			panic("lost the connection")
+		}, "panicked", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			rb := newTestInstance(t, db, Options{})
+			task, err := rb.RegisterResultTask("import", tt.taskFunc, versionSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runID, err := task.ExecuteNow(TaskParams{"version": "v1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("import", tt.wantStatus)) == 1 }) {
+				t.Fatalf("run did not end as %s", tt.wantStatus)
+			}
+
+			rec := callAPI(t, rb.getExecution, http.MethodGet, "", "id", strconv.Itoa(runID))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body)
+			}
+			var execution TaskExecution
+			if err := json.Unmarshal(rec.Body.Bytes(), &execution); err != nil {
+				t.Fatal(err)
+			}
+			if execution.ID != runID || execution.Status != tt.wantStatus || string(execution.Result) != tt.wantResult {
+				t.Errorf("execution %d has status %q and result %s, want %d, %q and %s", execution.ID, execution.Status, execution.Result, runID, tt.wantStatus, tt.wantResult)
+			}
+		})
+	}
+
+	t.Run("unknown execution", func(t *testing.T) {
+		rb := newTestInstance(t, newMemoryDB(), Options{})
+		if rec := callAPI(t, rb.getExecution, http.MethodGet, "", "id", "42"); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", rec.Code)
+		}
+		if rec := callAPI(t, rb.getExecution, http.MethodGet, "", "id", "latest"); rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", rec.Code)
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package blueberry
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // TaskExecution represents the execution details of a task
 type TaskExecution struct {
@@ -11,6 +14,17 @@ type TaskExecution struct {
 	Duration  string                 `json:"duration"`
 	Params    map[string]interface{} `json:"params"`
 	Status    string                 `json:"status"`
+	CatchUp   bool                   `json:"catch_up"`
+	RunAt     time.Time              `json:"run_at"`
+	Attempt   int                    `json:"attempt"`
+	RetryOf   int                    `json:"retry_of"`
+	Priority  int                    `json:"priority"`
+
+	ScheduleID int           `json:"schedule_id,omitempty"`
+	Timeout    time.Duration `json:"timeout,omitempty" swaggertype:"integer"`
+
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	Result         json.RawMessage `json:"result,omitempty" swaggertype:"object"`
 }
 
 // TaskInfo represents the task and its schedules
@@ -29,6 +43,28 @@ type getTaskExecutionsResponse struct {
 
 type ExecuteTaskRequest struct {
 	Params TaskParams `json:"params"`
+
+	// Timeout in nanoseconds, overrides the timeout of the task for this run
+	Timeout time.Duration `json:"timeout,omitempty" swaggertype:"integer"`
+
+	// Priority overrides the priority of the task for this run when set, 0 included
+	Priority *int `json:"priority,omitempty"`
+
+	// IdempotencyKey deduplicates retried requests, the Idempotency-Key header can be used instead. At most 255 characters.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// ScheduleRequest is used to create or update a schedule of a task
+type ScheduleRequest struct {
+	Params   TaskParams `json:"params"`
+	Schedule string     `json:"schedule"`
+
+	ScheduleOptions
+}
+
+// MaintenanceMode is used to read or change the global maintenance mode
+type MaintenanceMode struct {
+	Enabled bool `json:"enabled"`
 }
 
 type ErrorResponse struct {
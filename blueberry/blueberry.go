@@ -2,27 +2,94 @@ package blueberry
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"reflect"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/labstack/gommon/log"
 	"github.com/robfig/cron/v3"
 )
 
+// ErrScheduleNotFound is returned when no schedule exists for the given schedule ID
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ScheduleInfo describes a registered schedule. ID is the stable identifier assigned by the store,
+// while EntryID is the cron entry of the current process and changes on every restart.
+// NextExecutionLocal is the next execution in RFC 3339 format, in the zone the schedule is evaluated in.
+// LastFired is the Unix timestamp of the last trigger, it is used to catch up on triggers missed while the process was down.
+// Occurrences counts the triggers that started, queued or deferred a run, Expired is set once the schedule used up
+// MaxOccurrences or passed EndAt.
+// Origin tells schedules registered from code apart from the ones created at runtime, see ScheduleOrigin.
 type ScheduleInfo struct {
-	Schedule      string                 `json:"schedule"`
-	Params        map[string]interface{} `json:"params"`
-	NextExecution int64                  `json:"next_execution_ts"`
-	EntryID       cron.EntryID           `json:"-"`
+	ID                 int                    `json:"id"`
+	TaskName           string                 `json:"task_name"`
+	Schedule           string                 `json:"schedule"`
+	Params             map[string]interface{} `json:"params"`
+	NextExecution      int64                  `json:"next_execution_ts"`
+	NextExecutionLocal string                 `json:"next_execution_local,omitempty" bson:"-"`
+	Paused             bool                   `json:"paused"`
+	LastFired          int64                  `json:"last_fired_ts"`
+	Occurrences        int                    `json:"occurrences"`
+	Origin             ScheduleOrigin         `json:"origin"`
+	Expired            bool                   `json:"expired" bson:"-"`
+	EntryID            cron.EntryID           `json:"-" bson:"-"`
+
+	ScheduleOptions `bson:",inline"`
 }
 
+// ScheduleOrigin records where a schedule was created. Schedules stored before origins were recorded have an empty
+// origin and are kept like the ones created at runtime.
+type ScheduleOrigin string
+
+const (
+	OriginCode ScheduleOrigin = "code" // Registered with RegisterSchedule before InitTaskScheduler, again on every start
+	OriginAPI  ScheduleOrigin = "api"  // Created at runtime through the API, the web UI, CreateSchedule or RegisterSchedule after InitTaskScheduler
+)
+
 type Task struct {
 	name      string
-	taskFunc  func(context.Context, TaskParams, *Logger) error
+	taskFunc  ResultTaskFunc // Functions without a result are wrapped to return nil
 	blueBerry *BlueBerry
 	schema    TaskSchema
+	options   TaskOptions
+
+	uniqueMux sync.Mutex // Serializes starting runs of a task with a unique policy
+}
+
+// TaskOptions holds the optional settings of a task
+type TaskOptions struct {
+	// Overlap is the default overlap policy of the task's schedules, see OverlapPolicy.
+	// Schedules can override it through ScheduleOptions. Defaults to OverlapAllow.
+	Overlap OverlapPolicy
+
+	// Retry retries failed runs of the task, see RetryPolicy. No retries by default.
+	Retry RetryPolicy
+
+	// Timeout ends runs that take longer with the "timed_out" status. Schedules and manual runs can override it.
+	// A task function that ignores its context keeps the concurrency slot and counts as running for overlap and
+	// unique checks until it returns, its log entries after the deadline are dropped. 0 means no timeout.
+	Timeout time.Duration
+
+	// MaxConcurrency limits the number of runs of the task executing at the same time. Further runs are queued
+	// with the "queued" status and start in order as runs finish. 0 means no limit.
+	MaxConcurrency int
+
+	// Priority orders queued runs, runs with a higher priority start first and equal priorities start in the order
+	// they were queued. Schedules and manual runs can override it. Defaults to 0.
+	Priority int
+
+	// Unique prevents a second run with the same parameters while one is queued or running, see UniquePolicy.
+	// Defaults to UniqueAllow.
+	Unique UniquePolicy
+
+	// UniqueKeys limits the parameters compared by Unique to these keys. All parameters are compared when empty.
+	UniqueKeys []string
 }
 
 type BlueBerry struct {
@@ -35,16 +102,95 @@ type BlueBerry struct {
 	schedules    sync.Map // To store schedules per task
 	executing    sync.Map // To track currently executing tasks
 
+	calendars sync.Map // Calendar name to Calendar
+
+	jitterSeed uint64 // Randomizes the jitter of schedule triggers per instance
+
+	pendingMux sync.Mutex
+	pending    map[int]*time.Timer // Run ID to the timer starting a delayed run
+
+	queueMux        sync.Mutex
+	queue           []*queuedRun   // Runs waiting for a slot, oldest first
+	active          int            // Runs holding a slot
+	activeTasks     map[string]int // Task name to the runs of the task holding a slot
+	activeSchedules map[int]int    // Schedule ID to the runs of the schedule holding a slot
+
+	options  Options
+	parser   cron.Parser    // parses cron expressions as configured by options
+	location *time.Location // default zone schedules are evaluated in
+	webPath  string         // base path the web UI is mounted at, set by GetEcho
+	started  atomic.Bool    // Set by InitTaskScheduler, schedules registered afterwards are runtime schedules
+
+	idempotencyMux   sync.Mutex // Guards idempotencyLocks
+	idempotencyLocks map[idempotencyLockKey]*idempotencyLock
+
+	maintenanceMux sync.RWMutex
+	maintenance    bool // While set, scheduled triggers are skipped
+
 	apiKeys          map[string]string
 	apiKeysMux       sync.RWMutex
 	usersMux         sync.RWMutex
 	webOnlyPasswords map[string]string
 }
 
+// SecondsField controls whether cron expressions carry a leading seconds field
+type SecondsField int
+
+const (
+	SecondsNone     SecondsField = iota // Standard 5 field expressions (default)
+	SecondsOptional                     // 5 or 6 field expressions, a missing seconds field means 0
+	SecondsRequired                     // 6 field expressions only
+)
+
+// Options configures a BlueBerry instance
+type Options struct {
+	// Location is the default time zone cron expressions are evaluated in.
+	// Schedules with their own Timezone override it. Defaults to the server's local time zone.
+	Location *time.Location
+
+	// Seconds enables a leading seconds field in cron expressions, e.g. "*/15 * * * * *".
+	Seconds SecondsField
+
+	// DisableDescriptors rejects descriptors such as "@daily" or "@every 1m".
+	// Note that the RunEvery* constants are descriptors.
+	DisableDescriptors bool
+
+	// OnPanic is called whenever a task function panics, e.g. to report it to an error tracker.
+	// The run itself is recorded as "panicked" either way.
+	OnPanic func(err *PanicError)
+
+	// MaxConcurrentRuns limits the number of runs of all tasks executing at the same time. Further runs are
+	// queued with the "queued" status and start in order as runs finish. 0 means no limit.
+	MaxConcurrentRuns int
+
+	// IdempotencyWindow is how long a manual run deduplicates requests with its idempotency key, see
+	// ExecuteOptions.IdempotencyKey. Defaults to 24 hours.
+	IdempotencyWindow time.Duration
+}
+
 func NewBlueBerryInstance(db DB) *BlueBerry {
+	return NewBlueBerryInstanceWithOptions(db, Options{})
+}
+
+// NewBlueBerryInstanceWithOptions creates a BlueBerry instance configured by opts
+func NewBlueBerryInstanceWithOptions(db DB, opts Options) *BlueBerry {
+	location := opts.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	parser := newCronParser(opts)
 	return &BlueBerry{
 		db:               db,
-		cron:             cron.New(),
+		cron:             cron.New(cron.WithLocation(location), cron.WithParser(parser)),
+		options:          opts,
+		parser:           parser,
+		location:         location,
+		pending:          make(map[int]*time.Timer),
+		activeTasks:      make(map[string]int),
+		activeSchedules:  make(map[int]int),
+		idempotencyLocks: make(map[idempotencyLockKey]*idempotencyLock),
+		jitterSeed:       rand.Uint64(),
 		apiKeys:          make(map[string]string),
 		webOnlyPasswords: make(map[string]string),
 	}
@@ -62,10 +208,57 @@ func (r *BlueBerry) AddAPIOnlyKeyAuth(apiKey, description string) {
 	r.apiKeys[apiKey] = description
 }
 
+// GetTask returns the registered task with the given name
+func (r *BlueBerry) GetTask(taskName string) (*Task, bool) {
+	taskInterface, ok := r.tasks.Load(taskName)
+	if !ok {
+		return nil, false
+	}
+	return taskInterface.(*Task), true
+}
+
 func (r *BlueBerry) RegisterTask(taskName string, taskFunc TaskFunc, schema TaskSchema) (*Task, error) {
+	return r.RegisterTaskWithOptions(taskName, taskFunc, schema, TaskOptions{})
+}
+
+// RegisterTaskWithOptions registers a task with optional settings such as the overlap policy of its schedules
+func (r *BlueBerry) RegisterTaskWithOptions(taskName string, taskFunc TaskFunc, schema TaskSchema, opts TaskOptions) (*Task, error) {
+	return r.RegisterResultTaskWithOptions(taskName, func(ctx context.Context, params TaskParams, logger *Logger) (any, error) {
+		return nil, taskFunc(ctx, params, logger)
+	}, schema, opts)
+}
+
+// RegisterResultTask registers a task whose function returns a result. The result must be JSON serializable, it is
+// stored with the run and returned by the API.
+func (r *BlueBerry) RegisterResultTask(taskName string, taskFunc ResultTaskFunc, schema TaskSchema) (*Task, error) {
+	return r.RegisterResultTaskWithOptions(taskName, taskFunc, schema, TaskOptions{})
+}
+
+// RegisterResultTaskWithOptions registers a task whose function returns a result with optional settings
+func (r *BlueBerry) RegisterResultTaskWithOptions(taskName string, taskFunc ResultTaskFunc, schema TaskSchema, opts TaskOptions) (*Task, error) {
 	if err := validateSchema(schema); err != nil {
 		return nil, err
 	}
+	if err := opts.Overlap.validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.Retry.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Timeout < 0 {
+		return nil, errors.New("timeout must not be negative")
+	}
+	if opts.MaxConcurrency < 0 {
+		return nil, errors.New("max concurrency must not be negative")
+	}
+	if err := opts.Unique.validate(); err != nil {
+		return nil, err
+	}
+	for _, key := range opts.UniqueKeys {
+		if _, ok := schema.Fields[key]; !ok {
+			return nil, fmt.Errorf("unique key %s is not a parameter of the task", key)
+		}
+	}
 
 	r.taskMux.Lock()
 	defer r.taskMux.Unlock()
@@ -73,6 +266,7 @@ func (r *BlueBerry) RegisterTask(taskName string, taskFunc TaskFunc, schema Task
 		name:      taskName,
 		taskFunc:  taskFunc,
 		schema:    schema,
+		options:   opts,
 		blueBerry: r,
 	}
 	r.tasks.Store(taskName, task)
@@ -126,6 +320,11 @@ func validateType(params TaskParams, key string, expectedType TaskParamType) err
 		if v.Kind() == reflect.Int {
 			return nil
 		}
+		if v.Kind() == reflect.Int32 || v.Kind() == reflect.Int64 {
+			// Stores such as MongoDB decode integers with an explicit size
+			params[key] = int(v.Int())
+			return nil
+		}
 		if v.Kind() == reflect.Float64 {
 			params[key] = int(value.(float64))
 			return nil
@@ -176,125 +375,654 @@ func validateType(params TaskParams, key string, expectedType TaskParamType) err
 }
 
 func (t *Task) RegisterSchedule(params TaskParams, schedule string) (ScheduleInfo, error) {
+	return t.RegisterScheduleWithOptions(params, schedule, ScheduleOptions{})
+}
+
+// RegisterScheduleWithOptions registers a schedule with optional settings such as its time zone.
+// Schedules registered before InitTaskScheduler are owned by the code: they are matched with their stored copy on
+// every start, and InitTaskScheduler deletes the stored ones that were not registered again, e.g. because their
+// expression changed. Schedules registered afterwards are created at runtime like with CreateSchedule, and are
+// restored until they are deleted.
+func (t *Task) RegisterScheduleWithOptions(params TaskParams, schedule string, opts ScheduleOptions) (ScheduleInfo, error) {
+	if t.blueBerry.started.Load() {
+		return t.addSchedule(params, schedule, opts, OriginAPI)
+	}
+	return t.addSchedule(params, schedule, opts, OriginCode)
+}
+
+func (t *Task) CreateSchedule(params TaskParams, schedule string) (ScheduleInfo, error) {
+	return t.CreateScheduleWithOptions(params, schedule, ScheduleOptions{})
+}
+
+// CreateScheduleWithOptions adds a schedule at runtime, e.g. for an API request. Unlike the schedules registered from
+// code it is restored by InitTaskScheduler until it is deleted.
+func (t *Task) CreateScheduleWithOptions(params TaskParams, schedule string, opts ScheduleOptions) (ScheduleInfo, error) {
+	return t.addSchedule(params, schedule, opts, OriginAPI)
+}
+
+func (t *Task) addSchedule(params TaskParams, schedule string, opts ScheduleOptions, origin ScheduleOrigin) (ScheduleInfo, error) {
 	if err := t.ValidateParams(params); err != nil {
 		return ScheduleInfo{}, err
 	}
-
-	entryID, err := t.blueBerry.cron.AddFunc(schedule, func() {
-		t.ExecuteNow(params)
-	})
-	if err != nil {
+	if _, err := t.blueBerry.parseScheduleWithOptions(schedule, opts); err != nil {
+		return ScheduleInfo{}, err
+	}
+	if err := t.blueBerry.validateParamTemplates(ScheduleInfo{TaskName: t.name, Schedule: schedule, Params: params, ScheduleOptions: opts}); err != nil {
 		return ScheduleInfo{}, err
 	}
 
-	// Store the schedule with the entry ID
 	scheduleInfo := ScheduleInfo{
-		Schedule:      schedule,
-		Params:        params,
-		NextExecution: t.blueBerry.cron.Entry(entryID).Next.UTC().Unix(),
-		EntryID:       entryID,
+		TaskName:        t.name,
+		Schedule:        schedule,
+		Params:          params,
+		Origin:          origin,
+		ScheduleOptions: opts,
+	}
+
+	// Schedules registered from code run again on every start, so reuse the
+	// persisted copy instead of storing a duplicate each time.
+	var persisted *ScheduleInfo
+	if origin == OriginCode {
+		var err error
+		if persisted, err = t.findPersistedSchedule(params, schedule, opts); err != nil {
+			return ScheduleInfo{}, err
+		}
+	}
+	if persisted != nil {
+		scheduleInfo.ID = persisted.ID
+		scheduleInfo.Paused = persisted.Paused
+		scheduleInfo.LastFired = persisted.LastFired
+		scheduleInfo.Occurrences = persisted.Occurrences
+	}
+
+	// Save before handing the schedule to cron so that its triggers know the schedule ID.
+	// Copies stored without an origin are saved again to record it.
+	isNew := scheduleInfo.ID == 0
+	if isNew || persisted.Origin != origin {
+		if err := t.blueBerry.db.SaveSchedule(context.Background(), &scheduleInfo); err != nil {
+			return ScheduleInfo{}, fmt.Errorf("unable to save schedule: %w", err)
+		}
+	}
+
+	if !scheduleInfo.Paused {
+		if err := t.addToCron(&scheduleInfo); err != nil {
+			if isNew {
+				_ = t.blueBerry.db.DeleteSchedule(context.Background(), scheduleInfo.ID)
+			}
+			return ScheduleInfo{}, err
+		}
 	}
+
 	t.blueBerry.storeSchedule(t.name, scheduleInfo)
 
 	return scheduleInfo, nil
 }
 
-func (t *Task) DeleteSchedule(entryID cron.EntryID) {
+// addToCron registers the schedule with the cron engine and fills in its entry ID and next execution.
+// Expired schedules are not registered.
+func (t *Task) addToCron(scheduleInfo *ScheduleInfo) error {
+	parsed, err := t.blueBerry.cronSchedule(*scheduleInfo)
+	if err != nil {
+		return err
+	}
+	if scheduleInfo.expired(time.Now()) {
+		scheduleInfo.EntryID = 0
+		return nil
+	}
+
+	// The job works on a copy, the schedule is added to cron again whenever it changes
+	fired := *scheduleInfo
+	var entryID cron.EntryID
+	entryID = t.blueBerry.cron.Schedule(parsed, cron.FuncJob(func() {
+		// Prev is the time the entry was due, which is not delayed by the job start
+		firedAt := t.blueBerry.cron.Entry(entryID).Prev
+		if firedAt.IsZero() {
+			firedAt = time.Now()
+		}
+
+		scheduleInfo := fired
+		if delay := t.blueBerry.triggerDelay(scheduleInfo, firedAt); delay > 0 {
+			time.Sleep(time.Until(firedAt.Add(delay)))
+
+			// The schedule may have changed while the trigger was delayed
+			current, err := t.GetSchedule(fired.ID)
+			if err != nil || current.Paused {
+				log.Infof("dropping delayed trigger of schedule %d of task %s: schedule was removed or paused", fired.ID, t.name)
+				return
+			}
+			scheduleInfo = current
+		}
+		if !t.blueBerry.scheduleActive(t.name, fired.ID) {
+			return
+		}
+
+		occurrences := 0
+		if t.fireSchedule(scheduleInfo, firedAt, false) {
+			occurrences = 1
+		}
+		t.blueBerry.recordScheduleFired(t.name, fired.ID, firedAt, occurrences)
+	}))
+
+	scheduleInfo.EntryID = entryID
+	t.blueBerry.setNextExecution(scheduleInfo)
+	return nil
+}
+
+// findPersistedSchedule looks for a stored schedule of this task registered from code with the same expression and
+// parameters that has not been registered in this process yet.
+func (t *Task) findPersistedSchedule(params TaskParams, schedule string, opts ScheduleOptions) (*ScheduleInfo, error) {
+	persisted, err := t.blueBerry.db.GetSchedules(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("unable to load schedules: %w", err)
+	}
+
+	for i := range persisted {
+		candidate := persisted[i]
+		if candidate.Origin == OriginAPI {
+			continue
+		}
+		if candidate.TaskName != t.name || candidate.Schedule != schedule || !candidate.ScheduleOptions.equal(opts) {
+			continue
+		}
+		if t.blueBerry.isScheduleRegistered(t.name, candidate.ID) {
+			continue
+		}
+		candidateParams := TaskParams(candidate.Params)
+		if err := t.ValidateParams(candidateParams); err != nil {
+			continue
+		}
+		if sameParams(candidateParams, params) {
+			return &candidate, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func sameParams(a, b TaskParams) bool {
+	aJSON, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bJSON, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(aJSON) == string(bJSON)
+}
+
+// DeleteSchedule removes the schedule with the given schedule ID from the cron engine and the store
+func (t *Task) DeleteSchedule(scheduleID int) error {
 	t.blueBerry.schedulesMux.Lock()
 	defer t.blueBerry.schedulesMux.Unlock()
 
-	// Remove from CRON
-	t.blueBerry.cron.Remove(entryID)
+	schedules, ok := t.blueBerry.schedules.Load(t.name)
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrScheduleNotFound, scheduleID)
+	}
+
+	var deleted *ScheduleInfo
+	updatedSchedules := make([]ScheduleInfo, 0)
+	for _, schedule := range schedules.([]ScheduleInfo) {
+		if schedule.ID != scheduleID {
+			updatedSchedules = append(updatedSchedules, schedule)
+			continue
+		}
+		deleted = &schedule
+	}
+	if deleted == nil {
+		return fmt.Errorf("%w: %d", ErrScheduleNotFound, scheduleID)
+	}
+
+	// Remove from the store first, so that a failure leaves the schedule running and listed as it is
+	if err := t.blueBerry.db.DeleteSchedule(context.Background(), scheduleID); err != nil {
+		return fmt.Errorf("unable to delete schedule: %w", err)
+	}
+
+	// Map the stable schedule ID to the cron entry of this process
+	t.blueBerry.cron.Remove(deleted.EntryID)
 
 	// Remove from the local schedule database (So that it's not shown in web client)
-	if schedules, ok := t.blueBerry.schedules.Load(t.name); ok {
-		updatedSchedules := make([]ScheduleInfo, 0)
-		for _, schedule := range schedules.([]ScheduleInfo) {
-			if schedule.EntryID != entryID {
-				updatedSchedules = append(updatedSchedules, schedule)
-			}
+	t.blueBerry.schedules.Store(t.name, updatedSchedules)
+	return nil
+}
+
+// GetSchedule returns the schedule with the given schedule ID
+func (t *Task) GetSchedule(scheduleID int) (ScheduleInfo, error) {
+	for _, schedule := range t.blueBerry.getSchedules(t.name) {
+		if schedule.ID == scheduleID {
+			return schedule, nil
 		}
-		t.blueBerry.schedules.Store(t.name, updatedSchedules)
 	}
+	return ScheduleInfo{}, fmt.Errorf("%w: %d", ErrScheduleNotFound, scheduleID)
+}
+
+// runOptions describes how a run was started
+type runOptions struct {
+	scheduleID int  // Schedule that triggered the run, 0 for manual runs
+	catchUp    bool // The run makes up for a trigger missed while the process was down
+	attempt    int  // Attempt number of a retried run, 0 for the first attempt
+	retryOf    int  // Run ID of the first attempt of a retried run
+
+	timeout        time.Duration // Overrides the timeout of the task when set
+	priority       *int          // Overrides the priority of the task when set
+	idempotencyKey string        // Key of the manual run request, not passed on to retries
+
+	// The run of a schedule with the queue overlap policy waits until the other runs of its schedule have
+	// finished and counts as an occurrence of the schedule once it starts
+	overlapQueue bool
+}
+
+// ExecuteOptions holds the optional settings of a manual run
+type ExecuteOptions struct {
+	// Timeout overrides the timeout of the task for this run
+	Timeout time.Duration
+
+	// Priority overrides the priority of the task for this run when set, e.g. to let an operator's run jump the
+	// queue. Any value including 0 overrides it, nil keeps the priority of the task.
+	Priority *int
+
+	// IdempotencyKey makes repeated requests start a single run. While a run of the task with the same key started
+	// within Options.IdempotencyWindow, its run ID is returned instead of starting another run. Keys are at most
+	// 255 characters long.
+	IdempotencyKey string
+}
+
+// execution tracks a run that is currently executing
+type execution struct {
+	runID      int
+	taskName   string
+	scheduleID int    // Schedule that triggered the run, 0 for manual runs
+	uniqueKey  string // Parameters compared by the unique policy of the task
+	cancel     context.CancelFunc
+	done       chan struct{} // Closed once the run has finished
+	ended      atomic.Bool   // Set once a timed out run ended while its task function is still running
 }
 
 func (t *Task) ExecuteNow(params TaskParams) (int, error) {
-	if err := t.ValidateParams(params); err != nil {
+	return t.ExecuteNowWithOptions(params, ExecuteOptions{})
+}
+
+// ExecuteNowWithOptions starts a run of the task right away with optional settings such as a timeout
+func (t *Task) ExecuteNowWithOptions(params TaskParams, opts ExecuteOptions) (int, error) {
+	if opts.Timeout < 0 {
+		return 0, errors.New("timeout must not be negative")
+	}
+
+	if err := validateIdempotencyKey(opts.IdempotencyKey); err != nil {
+		return 0, err
+	}
+
+	if opts.IdempotencyKey != "" {
+		// Held until the run is stored so that concurrent repeats find it
+		unlock := t.blueBerry.lockIdempotencyKey(t.name, opts.IdempotencyKey)
+		defer unlock()
+
+		runID, err := t.idempotentRun(opts.IdempotencyKey)
+		if err != nil || runID != 0 {
+			return runID, err
+		}
+	}
+
+	exec, err := t.execute(params, runOptions{timeout: opts.Timeout, priority: opts.Priority, idempotencyKey: opts.IdempotencyKey})
+	var alreadyRunning *AlreadyRunningError
+	if errors.As(err, &alreadyRunning) && t.options.Unique == UniqueReuse {
+		if opts.IdempotencyKey != "" {
+			// Repeats of the request return the reused run as well instead of starting one once it has finished
+			if err := t.blueBerry.db.SaveIdempotencyKey(context.Background(), t.name, opts.IdempotencyKey, alreadyRunning.RunID); err != nil {
+				return 0, fmt.Errorf("unable to save idempotency key: %w", err)
+			}
+		}
+		return alreadyRunning.RunID, nil
+	}
+	if err != nil {
 		return 0, err
 	}
+	return exec.runID, nil
+}
+
+// execute starts a run of the task in the background. Tasks with a unique policy return an *AlreadyRunningError
+// instead when a run with the same parameters is queued or running.
+func (t *Task) execute(params TaskParams, opts runOptions) (*execution, error) {
+	if err := t.ValidateParams(params); err != nil {
+		return nil, err
+	}
 
 	taskRun := &TaskRun{
-		TaskName:  t.name,
-		StartTime: time.Now().UTC(),
-		Params:    params,
-		Status:    "started",
+		TaskName: t.name,
+		Params:   params,
+		CatchUp:  opts.catchUp,
+		Priority: t.priority(opts),
+
+		ScheduleID: opts.scheduleID,
+		Timeout:    t.timeout(opts),
+
+		IdempotencyKey: opts.idempotencyKey,
+	}
+	return t.runUnique(taskRun, opts)
+}
+
+// runUnique starts the run unless the unique policy of the task finds a queued or running run with the same
+// parameters, in which case an *AlreadyRunningError is returned
+func (t *Task) runUnique(taskRun *TaskRun, opts runOptions) (*execution, error) {
+	if t.unique() {
+		// Held until the run is tracked so that concurrent starts see it
+		t.uniqueMux.Lock()
+		defer t.uniqueMux.Unlock()
+
+		if runID := t.blueBerry.duplicateRun(t.name, t.uniqueKey(taskRun.Params)); runID != 0 {
+			return nil, &AlreadyRunningError{TaskName: t.name, RunID: runID}
+		}
+	}
+	return t.run(taskRun, opts)
+}
+
+// priority returns the priority of a run started with the given options
+func (t *Task) priority(opts runOptions) int {
+	if opts.priority != nil {
+		return *opts.priority
+	}
+	return t.options.Priority
+}
+
+// timeout returns the timeout of a run started with the given options, 0 means no timeout
+func (t *Task) timeout(opts runOptions) time.Duration {
+	if opts.timeout > 0 {
+		return opts.timeout
+	}
+	return t.options.Timeout
+}
+
+// run starts the task run in the background, or queues it while the concurrency limits are reached
+func (t *Task) run(taskRun *TaskRun, opts runOptions) (*execution, error) {
+	exec := &execution{
+		taskName:   t.name,
+		scheduleID: opts.scheduleID,
+		uniqueKey:  t.uniqueKey(taskRun.Params),
+		done:       make(chan struct{}),
+	}
+	if !t.blueBerry.acquireSlot(t, opts) {
+		if err := t.blueBerry.queueRun(t, taskRun, opts, exec); err != nil {
+			return nil, err
+		}
+		return exec, nil
+	}
+
+	if err := t.start(taskRun, opts, exec); err != nil {
+		t.blueBerry.releaseSlot(t, opts.scheduleID)
+		return nil, err
+	}
+	return exec, nil
+}
+
+// start marks the task run as started, stores it and runs the task in the background. The caller holds a slot,
+// which is released once the run has finished.
+func (t *Task) start(taskRun *TaskRun, opts runOptions, exec *execution) error {
+	if opts.overlapQueue && !t.blueBerry.recordScheduleFired(t.name, opts.scheduleID, time.Time{}, 1) {
+		t.skipPendingRun(taskRun, fmt.Sprintf("Skipped queued trigger of schedule %d: schedule was removed or has no occurrences left", opts.scheduleID))
+		exec.runID = taskRun.ID
+		close(exec.done)
+		t.blueBerry.releaseSlot(t, opts.scheduleID)
+		return nil
+	}
+
+	taskRun.StartTime = time.Now().UTC()
+	taskRun.Status = "started"
+	if taskRun.Attempt == 0 {
+		taskRun.Attempt = 1
 	}
 
 	err := t.blueBerry.db.SaveTaskRun(context.Background(), taskRun)
 	if err != nil {
 		fmt.Printf("unable to log task start: %v\n", err)
-		return 0, err
+		return err
+	}
+
+	timeout := t.timeout(opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	}
+	exec.runID = taskRun.ID
+	exec.cancel = cancel
+	// Track the run before it starts so that overlapping triggers see it immediately
+	t.blueBerry.executing.Store(taskRun.ID, exec)
 
 	go func(taskRun *TaskRun, params TaskParams) {
-		ctx, cancel := context.WithCancel(context.Background())
+		defer close(exec.done)
+		defer t.blueBerry.releaseSlot(t, opts.scheduleID)
 		defer cancel()
 
-		t.blueBerry.executing.Store(taskRun.ID, cancel)
-		defer t.blueBerry.executing.Delete(taskRun.ID)
-
 		logger := &Logger{taskRun: taskRun, db: t.blueBerry.db}
-		err = t.taskFunc(ctx, params, logger)
-		if err != nil {
+		// The task function gets its own logger, which is ended when the run times out
+		taskLogger := &Logger{taskRun: taskRun, db: t.blueBerry.db}
+		var value any // Only read once the task function returned
+		result := make(chan error, 1)
+		go func() {
+			result <- t.callTaskFunc(taskRun, func() (err error) {
+				value, err = t.taskFunc(ctx, params, taskLogger)
+				return err
+			})
+		}()
+
+		var runErr error
+		var panicErr *PanicError
+		timedOut := false
+		returned := true
+		select {
+		case runErr = <-result:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// Tasks ignoring their context would keep the run open forever, so it ends at the deadline
+				timedOut = true
+				returned = false
+				exec.ended.Store(true)
+				taskLogger.end()
+			} else {
+				runErr = <-result
+			}
+		}
+		// A panic wins over a cancellation that happened meanwhile
+		panicked := errors.As(runErr, &panicErr)
+		if !panicked && runErr != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			timedOut = true
+		}
+
+		if returned && value != nil {
+			encoded, err := json.Marshal(value)
+			if err != nil && runErr == nil {
+				runErr = fmt.Errorf("result is not JSON serializable: %w", err)
+			}
+			taskRun.Result = encoded
+		}
+
+		switch {
+		case panicked:
+			taskRun.Status = "panicked"
+			_ = logger.Errorf("Task panicked: %v\n%s", panicErr.Value, panicErr.Stack)
+			t.blueBerry.reportPanic(panicErr)
+		case timedOut:
+			taskRun.Status = "timed_out"
+			runErr = ctx.Err()
+			_ = logger.Errorf("Task timed out after %s", timeout)
+		case errors.Is(ctx.Err(), context.Canceled):
+			// Cancelled through CancelExecutionByID or Shutdown, which already recorded it
+			taskRun.Status = "cancelled"
+		case runErr != nil:
 			taskRun.Status = "failed"
-			_ = logger.Error("Task failed due to: " + err.Error())
-		} else {
+			_ = logger.Error("Task failed due to: " + runErr.Error())
+		default:
 			taskRun.Status = "completed"
 		}
 		taskRun.EndTime = time.Now().UTC()
 
-		err = t.blueBerry.db.SaveTaskRun(ctx, taskRun)
+		err := t.blueBerry.db.SaveTaskRun(context.Background(), taskRun)
 		if err != nil {
 			_ = logger.Error("Unable to save task run due to: " + err.Error())
 		}
-	}(taskRun, params)
 
-	return taskRun.ID, nil
+		if !returned {
+			// The slot stays taken and the run is seen by overlap and unique checks until the task function returns
+			_ = logger.Info("Waiting for the task function to return before the next run can take its slot")
+			// The run already ended as timed out, a panic on the way out is still logged and reported
+			var latePanic *PanicError
+			if lateErr := <-result; errors.As(lateErr, &latePanic) {
+				_ = logger.Errorf("Task panicked after it timed out: %v\n%s", latePanic.Value, latePanic.Stack)
+				t.blueBerry.reportPanic(latePanic)
+			}
+		}
+		t.blueBerry.executing.Delete(taskRun.ID)
+
+		if taskRun.Status == "failed" || taskRun.Status == "timed_out" || taskRun.Status == "panicked" {
+			t.retry(taskRun, opts, runErr, logger)
+		}
+	}(taskRun, taskRun.Params)
+
+	return nil
+}
+
+// runningForSchedule returns the runs triggered by the given schedule that are still executing or queued
+func (r *BlueBerry) runningForSchedule(scheduleID int) []*execution {
+	running := r.queuedForSchedule(scheduleID)
+	r.executing.Range(func(_, value interface{}) bool {
+		exec := value.(*execution)
+		if exec.scheduleID == scheduleID {
+			running = append(running, exec)
+		}
+		return true
+	})
+	return running
 }
 
 func (r *BlueBerry) storeSchedule(taskName string, scheduleInfo ScheduleInfo) {
+	r.schedulesMux.Lock()
+	defer r.schedulesMux.Unlock()
+
 	schedules, _ := r.schedules.LoadOrStore(taskName, []ScheduleInfo{})
 	schedules = append(schedules.([]ScheduleInfo), scheduleInfo)
 	r.schedules.Store(taskName, schedules)
 }
 
 func (r *BlueBerry) getSchedules(taskName string) []ScheduleInfo {
+	r.schedulesMux.RLock()
+	defer r.schedulesMux.RUnlock()
+
 	loadedSchedules, ok := r.schedules.Load(taskName)
 	if !ok {
 		return nil
 	}
 
-	schedules := loadedSchedules.([]ScheduleInfo)
+	// Copy so that callers never share the backing array with the stored slice
+	schedules := append([]ScheduleInfo(nil), loadedSchedules.([]ScheduleInfo)...)
 
+	now := time.Now()
 	for i := range schedules {
-		// Retrieve the next execution time using the entry ID
-		entry := r.cron.Entry(schedules[i].EntryID)
-		schedules[i].NextExecution = entry.Next.UTC().Unix()
+		schedules[i].Expired = schedules[i].expired(now)
+		if schedules[i].Paused || schedules[i].Expired {
+			schedules[i].NextExecution = 0
+			schedules[i].NextExecutionLocal = ""
+			continue
+		}
+
+		r.setNextExecution(&schedules[i])
 	}
 
 	return schedules
 }
 
-func (r *BlueBerry) InitTaskScheduler() {
+func (r *BlueBerry) isScheduleRegistered(taskName string, scheduleID int) bool {
+	r.schedulesMux.RLock()
+	defer r.schedulesMux.RUnlock()
+
+	schedules, ok := r.schedules.Load(taskName)
+	if !ok {
+		return false
+	}
+	for _, schedule := range schedules.([]ScheduleInfo) {
+		if schedule.ID == scheduleID {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSchedules restores the schedules persisted in the store that were created at runtime. Stored schedules
+// registered from code that were not registered again in this process are outdated, they are deleted.
+func (r *BlueBerry) loadSchedules() error {
+	schedules, err := r.db.GetSchedules(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to load schedules: %w", err)
+	}
+
+	for _, scheduleInfo := range schedules {
+		if r.isScheduleRegistered(scheduleInfo.TaskName, scheduleInfo.ID) {
+			continue
+		}
+
+		if scheduleInfo.Origin == OriginCode {
+			log.Infof("deleting schedule %d of task %s: it is no longer registered from code", scheduleInfo.ID, scheduleInfo.TaskName)
+			if err := r.db.DeleteSchedule(context.Background(), scheduleInfo.ID); err != nil {
+				log.Warnf("unable to delete schedule %d: %v", scheduleInfo.ID, err)
+			}
+			continue
+		}
+
+		taskInterface, ok := r.tasks.Load(scheduleInfo.TaskName)
+		if !ok {
+			log.Warnf("skipping schedule %d: task %s is not registered", scheduleInfo.ID, scheduleInfo.TaskName)
+			continue
+		}
+		task := taskInterface.(*Task)
+
+		if err := task.ValidateParams(scheduleInfo.Params); err != nil {
+			log.Warnf("skipping schedule %d: %v", scheduleInfo.ID, err)
+			continue
+		}
+
+		// Paused schedules keep their configuration but are not handed to the cron engine
+		if !scheduleInfo.Paused {
+			if err := task.addToCron(&scheduleInfo); err != nil {
+				log.Warnf("skipping schedule %d: %v", scheduleInfo.ID, err)
+				continue
+			}
+		}
+		r.storeSchedule(task.name, scheduleInfo)
+	}
+
+	return nil
+}
+
+// InitTaskScheduler restores the persisted schedules, catches up on the triggers missed while the process was down
+// and starts the cron engine
+func (r *BlueBerry) InitTaskScheduler() error {
+	r.started.Store(true)
+	if err := r.loadSchedules(); err != nil {
+		return err
+	}
+
+	abandoned := r.markAbandonedRuns()
+	r.restoreQueuedRuns()
+	// Restore the stored pending runs before catch-ups and retries add new ones, which are already scheduled
+	r.restorePendingRuns()
+	r.catchUpMisfires()
+	r.retryAbandonedRuns(abandoned)
+
 	r.cron.Start()
+	return nil
 }
 
 func (r *BlueBerry) Shutdown() {
+	// Delayed and queued runs stay in the store and are restored on the next start
+	r.stopPendingRuns()
+	r.stopQueuedRuns()
+
 	// Cancel all running tasks
 	r.executing.Range(func(key, value interface{}) bool {
 		executionID := key.(int)
-		cancel := value.(context.CancelFunc)
-		cancel()
+		value.(*execution).cancel()
+		if value.(*execution).ended.Load() {
+			return true // Timed out already, only its task function is still running
+		}
 
 		// Log the cancellation to the database
 		taskRun, err := r.db.GetTaskRunByID(context.Background(), executionID)
@@ -309,21 +1037,33 @@ func (r *BlueBerry) Shutdown() {
 }
 
 func (r *BlueBerry) CancelExecutionByID(executionID int) error {
-	cancel, ok := r.executing.Load(executionID)
-	if !ok {
+	if r.cancelPendingRun(executionID) || r.cancelQueuedRun(executionID) {
+		return r.markCancelled(executionID)
+	}
+
+	exec, ok := r.executing.Load(executionID)
+	if !ok || exec.(*execution).ended.Load() {
 		return fmt.Errorf("execution ID %d not found or already completed", executionID)
 	}
 
-	// Remove the cancel function from the map
+	// Remove the execution from the map
 	defer r.executing.Delete(executionID)
-	cancel.(context.CancelFunc)()
+	exec.(*execution).cancel()
+
+	return r.markCancelled(executionID)
+}
 
-	// Log the cancellation to the database
+// markCancelled logs the cancellation of a run to the database
+func (r *BlueBerry) markCancelled(executionID int) error {
 	taskRun, err := r.db.GetTaskRunByID(context.Background(), executionID)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve task run: %v", err)
 	}
 
+	if taskRun.Status == "pending" {
+		// The run never started, RunAt keeps the time it was planned for
+		taskRun.StartTime = time.Now().UTC()
+	}
 	taskRun.Status = "cancelled"
 	taskRun.EndTime = time.Now().UTC()
 	if err := r.db.SaveTaskRun(context.Background(), taskRun); err != nil {
@@ -0,0 +1,265 @@
+package blueberry
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+var versionSchema = NewTaskSchema(TaskParamDefinition{"version": TypeString})
+
+// newTestInstance creates an instance on db whose cron expressions accept a seconds field, it is shut down with the test
+func newTestInstance(t *testing.T, db DB, opts Options) *BlueBerry {
+	t.Helper()
+	opts.Seconds = SecondsOptional
+	rb := NewBlueBerryInstanceWithOptions(db, opts)
+	t.Cleanup(func() {
+		<-rb.cron.Stop().Done()
+		rb.Shutdown()
+	})
+	return rb
+}
+
+// runCounter counts the runs of a task by their version parameter
+type runCounter struct {
+	mu   sync.Mutex
+	runs map[string]int
+}
+
+func (c *runCounter) task(_ context.Context, params TaskParams, _ *Logger) error {
+	version, _ := params.GetString("version")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.runs == nil {
+		c.runs = make(map[string]int)
+	}
+	c.runs[version]++
+	return nil
+}
+
+func (c *runCounter) count(version string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.runs[version]
+}
+
+// waitFor polls cond until it holds or the timeout passes
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+func storedVersions(t *testing.T, db DB) []string {
+	t.Helper()
+	schedules, err := db.GetSchedules(context.Background())
+	if err != nil {
+		t.Fatalf("GetSchedules: %v", err)
+	}
+	var versions []string
+	for _, schedule := range schedules {
+		versions = append(versions, schedule.Params["version"].(string))
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+func TestChangedCodeScheduleReplacesStoredOne(t *testing.T) {
+	db := newMemoryDB()
+
+	first := newTestInstance(t, db, Options{})
+	task, err := first.RegisterTask("report", (&runCounter{}).task, versionSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := task.RegisterSchedule(TaskParams{"version": "old"}, "* * * * * *"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := task.CreateSchedule(TaskParams{"version": "api"}, "* * * * * *"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next process registers a changed schedule from code on the same store
+	counter := &runCounter{}
+	second := newTestInstance(t, db, Options{})
+	task, err = second.RegisterTask("report", counter.task, versionSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := task.RegisterSchedule(TaskParams{"version": "new"}, "* * * * * *"); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.InitTaskScheduler(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !waitFor(t, 3*time.Second, func() bool { return counter.count("new") > 0 && counter.count("api") > 0 }) {
+		t.Fatalf("new and api schedules did not fire: %v", counter.runs)
+	}
+	if n := counter.count("old"); n != 0 {
+		t.Errorf("removed code schedule fired %d times", n)
+	}
+	if got := storedVersions(t, db); len(got) != 2 || got[0] != "api" || got[1] != "new" {
+		t.Errorf("stored schedules = %v, want [api new]", got)
+	}
+}
+
+func TestUnchangedCodeScheduleKeepsStoredState(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin ScheduleOrigin // Origin of the stored copy
+	}{
+		{"registered from code", OriginCode},
+		{"stored before origins were recorded", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			stored := ScheduleInfo{TaskName: "report", Schedule: "@daily", Params: map[string]interface{}{"version": "v1"}, Paused: true, Origin: tt.origin}
+			if err := db.SaveSchedule(context.Background(), &stored); err != nil {
+				t.Fatal(err)
+			}
+
+			rb := newTestInstance(t, db, Options{})
+			task, _ := rb.RegisterTask("report", (&runCounter{}).task, versionSchema)
+			scheduleInfo, err := task.RegisterSchedule(TaskParams{"version": "v1"}, "@daily")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := rb.InitTaskScheduler(); err != nil {
+				t.Fatal(err)
+			}
+
+			if scheduleInfo.ID != stored.ID || !scheduleInfo.Paused {
+				t.Errorf("registered schedule %d (paused %v), want stored schedule %d (paused)", scheduleInfo.ID, scheduleInfo.Paused, stored.ID)
+			}
+			schedules, _ := db.GetSchedules(context.Background())
+			if len(schedules) != 1 || schedules[0].Origin != OriginCode {
+				t.Errorf("stored schedules = %+v, want the schedule with origin code", schedules)
+			}
+		})
+	}
+}
+
+func TestDeleteScheduleKeepsScheduleWhenStoreFails(t *testing.T) {
+	db := newMemoryDB()
+	rb := newTestInstance(t, db, Options{})
+	task, _ := rb.RegisterTask("report", (&runCounter{}).task, versionSchema)
+	scheduleInfo, err := task.CreateSchedule(TaskParams{"version": "v1"}, "@daily")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.deleteScheduleErr = errors.New("store unavailable")
+	if err := task.DeleteSchedule(scheduleInfo.ID); err == nil {
+		t.Fatal("DeleteSchedule succeeded although the store failed")
+	}
+	if _, err := task.GetSchedule(scheduleInfo.ID); err != nil {
+		t.Errorf("schedule is no longer listed: %v", err)
+	}
+	if entry := rb.cron.Entry(scheduleInfo.EntryID); !entry.Valid() {
+		t.Error("schedule was removed from the cron engine")
+	}
+
+	db.deleteScheduleErr = nil
+	if err := task.DeleteSchedule(scheduleInfo.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := task.GetSchedule(scheduleInfo.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("GetSchedule after delete = %v, want ErrScheduleNotFound", err)
+	}
+	if entry := rb.cron.Entry(scheduleInfo.EntryID); entry.Valid() {
+		t.Error("deleted schedule is still in the cron engine")
+	}
+}
+
+func TestTimedOutRunKeepsSlotUntilTaskReturns(t *testing.T) {
+	db := newMemoryDB()
+	rb := newTestInstance(t, db, Options{MaxConcurrentRuns: 1})
+	release := make(chan struct{})
+	task, err := rb.RegisterTaskWithOptions("hang", func(_ context.Context, params TaskParams, logger *Logger) error {
+		<-release // Ignores its context
+		_ = logger.Info("returned")
+		return nil
+	}, versionSchema, TaskOptions{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timedOut, err := task.ExecuteNow(TaskParams{"version": "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("hang", "timed_out")) == 1 }) {
+		t.Fatal("run did not time out")
+	}
+	next, err := task.ExecuteNow(TaskParams{"version": "v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queue := rb.GetQueue(); len(queue) != 1 || queue[0].ID != next {
+		t.Errorf("queue = %+v, want run %d waiting for the slot of the timed out run", queue, next)
+	}
+	if _, ok := rb.executing.Load(timedOut); !ok {
+		t.Error("timed out run is no longer tracked while its task function runs")
+	}
+	if err := rb.CancelExecutionByID(timedOut); err == nil {
+		t.Error("cancelling the timed out run succeeded")
+	}
+
+	close(release)
+	if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("hang", "completed")) == 1 }) {
+		t.Fatal("queued run did not start once the timed out task function returned")
+	}
+	if taskRun, _ := db.GetTaskRunByID(context.Background(), timedOut); taskRun.Status != "timed_out" {
+		t.Errorf("timed out run has status %s", taskRun.Status)
+	}
+	logs, _ := db.GetTaskRunLogs(context.Background(), timedOut)
+	for _, entry := range logs {
+		if entry.Message == "returned" {
+			t.Error("log entry written after the deadline was added to the timed out run")
+		}
+	}
+}
+
+func TestScheduleRegisteredAfterStartSurvivesRestart(t *testing.T) {
+	db := newMemoryDB()
+
+	first := newTestInstance(t, db, Options{})
+	task, _ := first.RegisterTask("report", (&runCounter{}).task, versionSchema)
+	if err := first.InitTaskScheduler(); err != nil {
+		t.Fatal(err)
+	}
+	// Registered at runtime, e.g. from a custom route
+	scheduleInfo, err := task.RegisterSchedule(TaskParams{"version": "runtime"}, "* * * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheduleInfo.Origin != OriginAPI {
+		t.Errorf("schedule registered after the start has origin %q, want %q", scheduleInfo.Origin, OriginAPI)
+	}
+
+	counter := &runCounter{}
+	second := newTestInstance(t, db, Options{})
+	if _, err := second.RegisterTask("report", counter.task, versionSchema); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.InitTaskScheduler(); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedVersions(t, db); len(got) != 1 || got[0] != "runtime" {
+		t.Errorf("stored schedules = %v, want [runtime]", got)
+	}
+	if !waitFor(t, 3*time.Second, func() bool { return counter.count("runtime") > 0 }) {
+		t.Error("restored runtime schedule did not fire")
+	}
+}
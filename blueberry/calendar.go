@@ -0,0 +1,253 @@
+package blueberry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// ErrCalendarNotFound is returned when no calendar is registered under the given name
+var ErrCalendarNotFound = errors.New("calendar not found")
+
+// CalendarPolicy decides what happens when a schedule triggers at a time excluded by its calendar
+type CalendarPolicy string
+
+const (
+	CalendarSkip  CalendarPolicy = "skip"  // Do not start a run, it is recorded as skipped (default)
+	CalendarDefer CalendarPolicy = "defer" // Start a delayed run at the next time the calendar allows
+)
+
+func (p CalendarPolicy) validate() error {
+	switch p {
+	case "", CalendarSkip, CalendarDefer:
+		return nil
+	}
+	return fmt.Errorf("unknown calendar policy %q", p)
+}
+
+// Calendar excludes days and times of day from the schedules it is attached to.
+// It is evaluated in the time zone of each schedule.
+type Calendar struct {
+	Name string `json:"name"`
+
+	// Dates are excluded days in the "2006-01-02" format, e.g. bank holidays
+	Dates []string `json:"dates,omitempty"`
+
+	// Weekdays are excluded every week, e.g. time.Saturday and time.Sunday
+	Weekdays []time.Weekday `json:"weekdays,omitempty" swaggertype:"array,integer"`
+
+	// Blackouts are times of day excluded on every day
+	Blackouts []Blackout `json:"blackouts,omitempty"`
+}
+
+// Blackout is a time of day window in the "15:04" format. The start is included and the end is not,
+// an end before the start spans midnight.
+type Blackout struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// calendarDateLayout is the format of Calendar.Dates
+const calendarDateLayout = "2006-01-02"
+
+// parseClock returns the minutes since midnight of a "15:04" time of day
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+func (c Calendar) validate() error {
+	if c.Name == "" {
+		return errors.New("calendar name must not be empty")
+	}
+	for _, date := range c.Dates {
+		if _, err := time.Parse(calendarDateLayout, date); err != nil {
+			return fmt.Errorf("calendar %s: invalid date %q, expected YYYY-MM-DD", c.Name, date)
+		}
+	}
+	for _, weekday := range c.Weekdays {
+		if weekday < time.Sunday || weekday > time.Saturday {
+			return fmt.Errorf("calendar %s: invalid weekday %d", c.Name, weekday)
+		}
+	}
+	for _, blackout := range c.Blackouts {
+		start, err := parseClock(blackout.Start)
+		if err != nil {
+			return fmt.Errorf("calendar %s: %v", c.Name, err)
+		}
+		end, err := parseClock(blackout.End)
+		if err != nil {
+			return fmt.Errorf("calendar %s: %v", c.Name, err)
+		}
+		if start == end {
+			return fmt.Errorf("calendar %s: blackout %s-%s is empty", c.Name, blackout.Start, blackout.End)
+		}
+	}
+	return nil
+}
+
+// exclusion returns why t is excluded by the calendar and the time the exclusion ends.
+// The reason is empty when t is allowed.
+func (c Calendar) exclusion(t time.Time) (string, time.Time) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	nextDay := midnight.AddDate(0, 0, 1)
+
+	date := t.Format(calendarDateLayout)
+	for _, excluded := range c.Dates {
+		if excluded == date {
+			return fmt.Sprintf("%s is an excluded date", date), nextDay
+		}
+	}
+	for _, weekday := range c.Weekdays {
+		if weekday == t.Weekday() {
+			return fmt.Sprintf("%s is an excluded weekday", weekday), nextDay
+		}
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, blackout := range c.Blackouts {
+		// Validated on registration
+		start, _ := parseClock(blackout.Start)
+		end, _ := parseClock(blackout.End)
+
+		reason := fmt.Sprintf("%s is in the blackout %s-%s", t.Format("15:04"), blackout.Start, blackout.End)
+		switch {
+		case start < end && minute >= start && minute < end:
+			return reason, clockTime(midnight, end)
+		case start > end && minute >= start:
+			return reason, clockTime(nextDay, end)
+		case start > end && minute < end:
+			return reason, clockTime(midnight, end)
+		}
+	}
+
+	return "", time.Time{}
+}
+
+// clockTime returns the wall clock time minute minutes after midnight on the given day. Unlike adding the minutes to
+// midnight it stays right on days the clocks are changed for daylight saving time.
+func clockTime(day time.Time, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, day.Location())
+}
+
+// nextAllowed returns the earliest time at or after t that the calendar does not exclude
+func (c Calendar) nextAllowed(t time.Time) (time.Time, error) {
+	// Each step skips at least one exclusion, a calendar that allows nothing for years is treated as exhausted
+	for i := 0; i < 5000; i++ {
+		reason, until := c.exclusion(t)
+		if reason == "" {
+			return t, nil
+		}
+		t = until
+	}
+	return time.Time{}, fmt.Errorf("calendar %s excludes every time after %s", c.Name, t.Format(time.RFC3339))
+}
+
+// RegisterCalendar registers a calendar that schedules can be attached to by name through ScheduleOptions.
+// Register calendars before the schedules using them and before InitTaskScheduler restores persisted schedules.
+// Registering a calendar under an existing name replaces it.
+func (r *BlueBerry) RegisterCalendar(calendar Calendar) error {
+	if err := calendar.validate(); err != nil {
+		return err
+	}
+	r.calendars.Store(calendar.Name, calendar)
+	return nil
+}
+
+// GetCalendar returns the calendar registered under the given name
+func (r *BlueBerry) GetCalendar(name string) (Calendar, bool) {
+	calendar, ok := r.calendars.Load(name)
+	if !ok {
+		return Calendar{}, false
+	}
+	return calendar.(Calendar), true
+}
+
+// listCalendars returns all registered calendars sorted by name
+func (r *BlueBerry) listCalendars() []Calendar {
+	calendars := []Calendar{}
+	r.calendars.Range(func(_, value interface{}) bool {
+		calendars = append(calendars, value.(Calendar))
+		return true
+	})
+	sort.Slice(calendars, func(i, j int) bool {
+		return calendars[i].Name < calendars[j].Name
+	})
+	return calendars
+}
+
+// calendarPolicy returns the calendar policy of the schedule, defaulting to CalendarSkip
+func (s ScheduleInfo) calendarPolicy() CalendarPolicy {
+	if s.CalendarPolicy != "" {
+		return s.CalendarPolicy
+	}
+	return CalendarSkip
+}
+
+// applyCalendar checks a trigger against the calendar of its schedule. It reports whether the trigger may start a
+// run right away, otherwise the trigger was recorded as skipped or deferred to the next allowed time, which is
+// reported as well.
+func (t *Task) applyCalendar(scheduleInfo ScheduleInfo, params TaskParams, firedAt time.Time, opts runOptions) (start, deferred bool) {
+	if scheduleInfo.Calendar == "" {
+		return true, false
+	}
+	calendar, ok := t.blueBerry.GetCalendar(scheduleInfo.Calendar)
+	if !ok {
+		log.Warnf("schedule %d uses unknown calendar %s, triggering anyway", scheduleInfo.ID, scheduleInfo.Calendar)
+		return true, false
+	}
+
+	firedAt = firedAt.In(t.blueBerry.scheduleLocation(scheduleInfo))
+	reason, _ := calendar.exclusion(firedAt)
+	if reason == "" {
+		return true, false
+	}
+
+	if scheduleInfo.calendarPolicy() == CalendarSkip {
+		t.recordSkippedRun(params, opts, fmt.Sprintf("Skipped trigger of schedule %d: %s of calendar %s", scheduleInfo.ID, reason, calendar.Name))
+		return false, false
+	}
+
+	next, err := calendar.nextAllowed(firedAt)
+	if err != nil {
+		t.recordSkippedRun(params, opts, fmt.Sprintf("Skipped trigger of schedule %d: %s of calendar %s and %v", scheduleInfo.ID, reason, calendar.Name, err))
+		return false, false
+	}
+
+	taskRun, err := t.executeAt(params, next, opts)
+	if err != nil {
+		log.Errorf("unable to defer trigger of schedule %d of task %s: %v", scheduleInfo.ID, t.name, err)
+		return false, false
+	}
+	logger := &Logger{taskRun: taskRun, db: t.blueBerry.db}
+	_ = logger.Infof("Deferred trigger of schedule %d from %s to %s: %s of calendar %s",
+		scheduleInfo.ID, firedAt.Format(time.RFC3339), next.Format(time.RFC3339), reason, calendar.Name)
+	return false, true
+}
+
+// startDeferredTrigger starts a trigger deferred by the calendar of its schedule once it is due. Like a trigger that
+// starts right away it is skipped in maintenance mode and by the overlap and unique policies.
+func (t *Task) startDeferredTrigger(taskRun *TaskRun, opts runOptions) {
+	if t.blueBerry.IsMaintenanceMode() {
+		t.skipPendingRun(taskRun, fmt.Sprintf("Skipped deferred trigger of schedule %d: maintenance mode is enabled", opts.scheduleID))
+		return
+	}
+	scheduleInfo, err := t.GetSchedule(opts.scheduleID)
+	if err != nil || scheduleInfo.Paused {
+		t.skipPendingRun(taskRun, fmt.Sprintf("Skipped deferred trigger of schedule %d: schedule was removed or paused", opts.scheduleID))
+		return
+	}
+
+	t.admitTrigger(scheduleInfo, func(reason string) {
+		t.skipPendingRun(taskRun, reason)
+	}, func(overlapQueue bool) *execution {
+		opts.overlapQueue = overlapQueue
+		return t.runPending(taskRun, opts)
+	})
+}
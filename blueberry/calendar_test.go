@@ -0,0 +1,173 @@
+package blueberry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCalendarNextAllowed(t *testing.T) {
+	calendar := Calendar{
+		Name:      "office",
+		Dates:     []string{"2026-12-25"},
+		Weekdays:  []time.Weekday{time.Saturday, time.Sunday},
+		Blackouts: []Blackout{{Start: "22:00", End: "06:00"}, {Start: "12:00", End: "13:00"}},
+	}
+	if err := calendar.validate(); err != nil {
+		t.Fatal(err)
+	}
+	at := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name string
+		t    string
+		want string
+	}{
+		{"allowed time is kept", "2026-10-14 10:00", "2026-10-14 10:00"},
+		{"blackout during the day", "2026-10-14 12:30", "2026-10-14 13:00"},
+		{"blackout before midnight", "2026-10-14 23:00", "2026-10-15 06:00"},
+		{"blackout after midnight", "2026-10-15 02:00", "2026-10-15 06:00"},
+		{"excluded weekdays", "2026-10-17 10:00", "2026-10-19 06:00"},
+		{"excluded date before a weekend", "2026-12-25 10:00", "2026-12-28 06:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calendar.nextAllowed(at(tt.t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := at(tt.want); !got.Equal(want) {
+				t.Errorf("nextAllowed(%s) = %s, want %s", tt.t, got.Format("2006-01-02 15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendarNextAllowedOnDaylightSavingDays(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data is not available: %v", err)
+	}
+	calendar := Calendar{
+		Name:      "night",
+		Blackouts: []Blackout{{Start: "22:00", End: "06:00"}, {Start: "00:30", End: "05:00"}},
+	}
+	if err := calendar.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		t    string
+		want string
+	}{
+		{"blackout into the day the clocks go forward", "2026-03-28 23:00", "2026-03-29 06:00"},
+		{"blackout on the day the clocks go forward", "2026-03-29 04:00", "2026-03-29 06:00"},
+		{"blackout into the day the clocks go back", "2026-10-24 23:00", "2026-10-25 06:00"},
+		{"blackout on the day the clocks go back", "2026-10-25 04:00", "2026-10-25 06:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.ParseInLocation("2006-01-02 15:04", tt.t, berlin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := calendar.nextAllowed(at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Format("2006-01-02 15:04") != tt.want {
+				t.Errorf("nextAllowed(%s) = %s, want %s", tt.t, got.Format("2006-01-02 15:04 MST"), tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendarValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar Calendar
+		wantErr  bool
+	}{
+		{"valid", Calendar{Name: "office", Dates: []string{"2026-12-25"}, Blackouts: []Blackout{{Start: "22:00", End: "06:00"}}}, false},
+		{"missing name", Calendar{}, true},
+		{"invalid date", Calendar{Name: "office", Dates: []string{"25.12.2026"}}, true},
+		{"invalid weekday", Calendar{Name: "office", Weekdays: []time.Weekday{7}}, true},
+		{"invalid time of day", Calendar{Name: "office", Blackouts: []Blackout{{Start: "25:00", End: "06:00"}}}, true},
+		{"empty blackout", Calendar{Name: "office", Blackouts: []Blackout{{Start: "06:00", End: "06:00"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.calendar.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeferredTriggerPassesScheduleChecks(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        TaskOptions
+		schedule    ScheduleOptions
+		maintenance bool
+		pause       bool
+		running     string // Version of a run of the schedule executing when the deferred trigger is due
+		wantStatus  string
+	}{
+		{"starts when nothing holds it back", TaskOptions{}, ScheduleOptions{}, false, false, "", "started"},
+		{"skipped in maintenance mode", TaskOptions{}, ScheduleOptions{}, true, false, "", "skipped"},
+		{"skipped when the schedule was paused", TaskOptions{}, ScheduleOptions{}, false, true, "", "skipped"},
+		{"skipped by the overlap policy", TaskOptions{}, ScheduleOptions{Overlap: OverlapSkip}, false, false, "other", "skipped"},
+		{"skipped by the unique policy", TaskOptions{Unique: UniqueReject}, ScheduleOptions{}, false, false, "v1", "skipped"},
+		{"overlapping runs are allowed by default", TaskOptions{}, ScheduleOptions{}, false, false, "other", "started"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			rb := newTestInstance(t, db, Options{})
+			task, err := rb.RegisterTaskWithOptions("report", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+				<-ctx.Done()
+				return nil
+			}, versionSchema, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scheduleInfo, err := task.CreateScheduleWithOptions(TaskParams{"version": "v1"}, "@daily", tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := runOptions{scheduleID: scheduleInfo.ID}
+			if tt.running != "" {
+				if _, err := task.execute(TaskParams{"version": tt.running}, opts); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.pause {
+				if _, err := task.PauseSchedule(scheduleInfo.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rb.SetMaintenanceMode(tt.maintenance)
+
+			// The calendar of the schedule deferred the trigger to a time that is due right away
+			deferred, err := task.executeAt(TaskParams{"version": "v1"}, time.Now().Add(20*time.Millisecond), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !waitFor(t, 3*time.Second, func() bool {
+				taskRun, err := db.GetTaskRunByID(context.Background(), deferred.ID)
+				return err == nil && taskRun.Status == tt.wantStatus
+			}) {
+				taskRun, _ := db.GetTaskRunByID(context.Background(), deferred.ID)
+				t.Errorf("deferred trigger has status %s, want %s", taskRun.Status, tt.wantStatus)
+			}
+		})
+	}
+}
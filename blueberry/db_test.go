@@ -0,0 +1,213 @@
+package blueberry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// memoryDB is an in-memory DB for tests. Parameters go through JSON like in the real stores, so restored runs and
+// schedules lose their Go types the same way.
+type memoryDB struct {
+	mu        sync.Mutex
+	taskRuns  map[int]TaskRun
+	logs      []TaskRunLog
+	schedules map[int]ScheduleInfo
+	lastRunID int
+	lastLogID int
+	lastSchID int
+
+	idempotencyKeys map[string]int // Task name and key to the run the key was saved for
+
+	deleteScheduleErr error // Returned by DeleteSchedule when set
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{
+		taskRuns:        make(map[int]TaskRun),
+		schedules:       make(map[int]ScheduleInfo),
+		idempotencyKeys: make(map[string]int),
+	}
+}
+
+// jsonParams returns a copy of params as a store would return it
+func jsonParams(params map[string]interface{}) map[string]interface{} {
+	encoded, _ := json.Marshal(params)
+	var decoded map[string]interface{}
+	_ = json.Unmarshal(encoded, &decoded)
+	return decoded
+}
+
+func (db *memoryDB) SaveTaskRun(_ context.Context, taskRun *TaskRun) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if taskRun.ID == 0 {
+		db.lastRunID++
+		taskRun.ID = db.lastRunID
+	}
+	stored := *taskRun
+	stored.Params = jsonParams(taskRun.Params)
+	db.taskRuns[taskRun.ID] = stored
+	return nil
+}
+
+func (db *memoryDB) SaveTaskRunLog(_ context.Context, taskRunLog *TaskRunLog) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.lastLogID++
+	taskRunLog.ID = db.lastLogID
+	db.logs = append(db.logs, *taskRunLog)
+	return nil
+}
+
+// sortedRuns returns the stored runs matching keep, oldest first, the caller holds mu
+func (db *memoryDB) sortedRuns(keep func(TaskRun) bool) []TaskRun {
+	taskRuns := []TaskRun{}
+	for _, taskRun := range db.taskRuns {
+		if keep(taskRun) {
+			taskRun.Params = jsonParams(taskRun.Params)
+			taskRuns = append(taskRuns, taskRun)
+		}
+	}
+	sort.Slice(taskRuns, func(i, j int) bool {
+		return taskRuns[i].ID < taskRuns[j].ID
+	})
+	return taskRuns
+}
+
+func (db *memoryDB) GetTaskRuns(_ context.Context) ([]TaskRun, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.sortedRuns(func(TaskRun) bool { return true }), nil
+}
+
+func (db *memoryDB) GetTaskRunsByStatus(_ context.Context, status string) ([]TaskRun, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.sortedRuns(func(taskRun TaskRun) bool { return taskRun.Status == status }), nil
+}
+
+func (db *memoryDB) GetTaskRunByID(_ context.Context, id int) (*TaskRun, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	taskRun, ok := db.taskRuns[id]
+	if !ok {
+		return nil, fmt.Errorf("task run with ID %d not found", id)
+	}
+	taskRun.Params = jsonParams(taskRun.Params)
+	return &taskRun, nil
+}
+
+func (db *memoryDB) GetTaskRunByIdempotencyKey(_ context.Context, taskName, key string) (*TaskRun, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	savedID := db.idempotencyKeys[taskName+"\x00"+key]
+	taskRuns := db.sortedRuns(func(taskRun TaskRun) bool {
+		return taskRun.TaskName == taskName && (taskRun.IdempotencyKey == key || taskRun.ID == savedID)
+	})
+	if len(taskRuns) == 0 {
+		return nil, nil
+	}
+	return &taskRuns[len(taskRuns)-1], nil
+}
+
+func (db *memoryDB) SaveIdempotencyKey(_ context.Context, taskName, key string, taskRunID int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.idempotencyKeys[taskName+"\x00"+key] = taskRunID
+	return nil
+}
+
+func (db *memoryDB) GetTaskRunLogs(_ context.Context, taskRunID int) ([]TaskRunLog, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	logs := []TaskRunLog{}
+	for _, log := range db.logs {
+		if log.TaskRunID == taskRunID {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (db *memoryDB) GetPaginatedTaskRunLogs(ctx context.Context, taskRunID int, level string, page, size int) ([]TaskRunLog, int, error) {
+	logs, _ := db.GetTaskRunLogs(ctx, taskRunID)
+	return logs, len(logs), nil
+}
+
+func (db *memoryDB) GetPaginatedTaskRunsForTaskName(_ context.Context, name string, page, limit int) ([]TaskRun, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.sortedRuns(func(taskRun TaskRun) bool { return taskRun.TaskName == name }), nil
+}
+
+func (db *memoryDB) GetTaskRunsCountForTaskName(ctx context.Context, name string) (int, error) {
+	taskRuns, _ := db.GetPaginatedTaskRunsForTaskName(ctx, name, 1, 0)
+	return len(taskRuns), nil
+}
+
+func (db *memoryDB) SaveSchedule(_ context.Context, schedule *ScheduleInfo) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if schedule.ID == 0 {
+		db.lastSchID++
+		schedule.ID = db.lastSchID
+	}
+	stored := *schedule
+	stored.Params = jsonParams(schedule.Params)
+	stored.EntryID = 0
+	db.schedules[schedule.ID] = stored
+	return nil
+}
+
+func (db *memoryDB) GetSchedules(_ context.Context) ([]ScheduleInfo, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	schedules := []ScheduleInfo{}
+	for _, schedule := range db.schedules {
+		schedule.Params = jsonParams(schedule.Params)
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules, nil
+}
+
+func (db *memoryDB) DeleteSchedule(_ context.Context, id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.deleteScheduleErr != nil {
+		return db.deleteScheduleErr
+	}
+	delete(db.schedules, id)
+	return nil
+}
+
+func (db *memoryDB) Close() error {
+	return nil
+}
+
+// runsWithStatus returns the stored runs of the task with the given status, oldest first
+func (db *memoryDB) runsWithStatus(taskName, status string) []TaskRun {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.sortedRuns(func(taskRun TaskRun) bool {
+		return taskRun.TaskName == taskName && taskRun.Status == status
+	})
+}
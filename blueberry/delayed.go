@@ -0,0 +1,149 @@
+package blueberry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// ExecuteAt stores a run of the task that starts at the given time and returns its run ID.
+// Until it starts the run has the "pending" status and can be cancelled with CancelExecutionByID. The unique policy
+// of the task is checked when the run starts, a duplicate is recorded as skipped.
+// Pending runs survive restarts, runs that became due while the process was down start on InitTaskScheduler.
+func (t *Task) ExecuteAt(params TaskParams, at time.Time) (int, error) {
+	taskRun, err := t.executeAt(params, at, runOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return taskRun.ID, nil
+}
+
+// ExecuteAfter stores a run of the task that starts once the given delay has passed, see ExecuteAt
+func (t *Task) ExecuteAfter(params TaskParams, delay time.Duration) (int, error) {
+	return t.ExecuteAt(params, time.Now().Add(delay))
+}
+
+// executeAt stores a pending run and starts it at the given time
+func (t *Task) executeAt(params TaskParams, at time.Time, opts runOptions) (*TaskRun, error) {
+	if err := t.ValidateParams(params); err != nil {
+		return nil, err
+	}
+
+	// The start time holds the planned time until the run starts, so pending runs are listed with the others
+	taskRun := &TaskRun{
+		TaskName:  t.name,
+		StartTime: at.UTC(),
+		RunAt:     at.UTC(),
+		Params:    params,
+		Status:    "pending",
+		CatchUp:   opts.catchUp,
+		Attempt:   opts.attempt,
+		RetryOf:   opts.retryOf,
+		Priority:  t.priority(opts),
+
+		ScheduleID: opts.scheduleID,
+		Timeout:    t.timeout(opts),
+	}
+	if taskRun.Attempt == 0 {
+		taskRun.Attempt = 1
+	}
+	if err := t.blueBerry.db.SaveTaskRun(context.Background(), taskRun); err != nil {
+		return nil, err
+	}
+
+	t.blueBerry.schedulePendingRun(t, taskRun, opts)
+	return taskRun, nil
+}
+
+// schedulePendingRun starts the pending run once it is due
+func (r *BlueBerry) schedulePendingRun(task *Task, taskRun *TaskRun, opts runOptions) {
+	r.pendingMux.Lock()
+	defer r.pendingMux.Unlock()
+
+	if _, ok := r.pending[taskRun.ID]; ok {
+		return
+	}
+
+	// The timer may fire right away, it waits for the lock until the timer is tracked
+	r.pending[taskRun.ID] = time.AfterFunc(time.Until(taskRun.RunAt), func() {
+		if !r.cancelPendingRun(taskRun.ID) {
+			return // Cancelled in the meantime
+		}
+		task.startPendingRun(taskRun, opts)
+	})
+}
+
+// startPendingRun starts a pending run that became due. Triggers deferred by a calendar go through the checks of
+// their schedule first, retries of scheduled runs do not.
+func (t *Task) startPendingRun(taskRun *TaskRun, opts runOptions) {
+	if opts.scheduleID != 0 && opts.retryOf == 0 {
+		t.startDeferredTrigger(taskRun, opts)
+		return
+	}
+	t.runPending(taskRun, opts)
+}
+
+// runPending starts a pending run, it returns nil if the run did not start. A run with the same parameters that is
+// queued or running by then makes the unique policy of the task skip it, with UniqueReuse as well as UniqueReject.
+func (t *Task) runPending(taskRun *TaskRun, opts runOptions) *execution {
+	exec, err := t.runUnique(taskRun, opts)
+	var alreadyRunning *AlreadyRunningError
+	if errors.As(err, &alreadyRunning) {
+		t.skipPendingRun(taskRun, fmt.Sprintf("Skipped: run %d with the same parameters is still queued or running", alreadyRunning.RunID))
+		return nil
+	}
+	if err != nil {
+		log.Errorf("unable to start delayed run %d of task %s: %v", taskRun.ID, t.name, err)
+		return nil
+	}
+	return exec
+}
+
+// skipPendingRun ends a pending run that became due without starting it
+func (t *Task) skipPendingRun(taskRun *TaskRun, reason string) {
+	// RunAt keeps the time the run was planned for
+	taskRun.Status = "skipped"
+	taskRun.StartTime = time.Now().UTC()
+	taskRun.EndTime = taskRun.StartTime
+	if err := t.blueBerry.db.SaveTaskRun(context.Background(), taskRun); err != nil {
+		log.Errorf("unable to save skipped run %d of task %s: %v", taskRun.ID, t.name, err)
+		return
+	}
+	logger := &Logger{taskRun: taskRun, db: t.blueBerry.db}
+	_ = logger.Info(reason)
+}
+
+// cancelPendingRun stops the timer of a pending run, it reports whether the run was still pending
+func (r *BlueBerry) cancelPendingRun(runID int) bool {
+	r.pendingMux.Lock()
+	defer r.pendingMux.Unlock()
+
+	timer, ok := r.pending[runID]
+	if !ok {
+		return false
+	}
+	timer.Stop()
+	delete(r.pending, runID)
+	return true
+}
+
+// stopPendingRuns stops the timers of all pending runs without changing their stored status
+func (r *BlueBerry) stopPendingRuns() {
+	r.pendingMux.Lock()
+	defer r.pendingMux.Unlock()
+
+	for runID, timer := range r.pending {
+		timer.Stop()
+		delete(r.pending, runID)
+	}
+}
+
+// restorePendingRuns schedules the runs that were still pending when the process stopped
+func (r *BlueBerry) restorePendingRuns() {
+	r.restoreRuns("pending", func(task *Task, taskRun *TaskRun) {
+		r.schedulePendingRun(task, taskRun, restoredRunOptions(taskRun))
+	})
+}
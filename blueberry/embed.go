@@ -3,6 +3,7 @@ package blueberry
 import (
 	"embed"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"io"
 	"strings"
@@ -45,8 +46,17 @@ func formatTimestamp(timestamp int64) string {
 	return time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
 }
 
-// loadTemplates loads and parses the templates with additional functions
-func loadTemplates(basePath string) (*template.Template, error) {
+// Format a task parameter value for a form input, nil values are left empty
+func formatParam(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// loadTemplates loads and parses the templates with additional functions.
+// maintenanceMode is consulted on every render so the navbar reflects the current state.
+func loadTemplates(basePath string, maintenanceMode func() bool) (*template.Template, error) {
 	var basePathWithoutSlash string
 
 	if strings.HasSuffix(basePath, "/") {
@@ -60,9 +70,11 @@ func loadTemplates(basePath string) (*template.Template, error) {
 		"sub":             sub,
 		"formatDateTime":  formatDateTime,
 		"formatTimestamp": formatTimestamp,
+		"formatParam":     formatParam,
 		"basePath": func() string {
 			return basePathWithoutSlash
 		},
+		"maintenanceMode": maintenanceMode,
 		"formatJSON": func(v interface{}) string {
 			b, err := json.MarshalIndent(v, "", "    ")
 			if err != nil {
@@ -2,6 +2,7 @@ package blueberry
 
 import (
 	"net/http"
+	"strings"
 
 	_ "github.com/ersauravadhikari/blueberry-go/docs"
 	"github.com/labstack/echo/v4"
@@ -22,7 +23,7 @@ func (r *BlueBerry) setupCore(basePath string) (*echo.Echo, error) {
 	e.Use(middleware.Recover())
 
 	// Load templates
-	templates, err := loadTemplates(basePath)
+	templates, err := loadTemplates(basePath, r.IsMaintenanceMode)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +55,9 @@ func (r *BlueBerry) GetEcho(cfg *Config) (*echo.Echo, error) {
 		}
 	}
 
+	// Remember where the web UI is mounted so that handlers can redirect within it
+	r.webPath = strings.TrimSuffix(webPath, "/")
+
 	// Setup Web UI routes
 	webGroup := e.Group(webPath)
 	r.setupWebRoutes(webGroup)
@@ -89,6 +93,15 @@ func (r *BlueBerry) setupWebRoutes(web *echo.Group) {
 	web.GET("/task/:name", r.showTask)
 	web.GET("/task/:name/run", r.executeTaskForm)
 	web.POST("/task/:name/execute", r.handleExecuteTask)
+	web.GET("/task/:name/schedules/new", r.newScheduleForm)
+	web.POST("/task/:name/schedules", r.handleCreateSchedule)
+	web.GET("/task/:name/schedules/:id/edit", r.editScheduleForm)
+	web.GET("/schedule/preview", r.showSchedulePreview)
+	web.POST("/task/:name/schedules/:id", r.handleUpdateSchedule)
+	web.POST("/task/:name/schedules/:id/delete", r.handleDeleteSchedule)
+	web.POST("/task/:name/schedules/:id/pause", r.handlePauseSchedule)
+	web.POST("/task/:name/schedules/:id/resume", r.handleResumeSchedule)
+	web.GET("/queue", r.showQueue)
 	web.GET("/execution/:id", r.showExecution)
 	web.POST("/execution/:id/cancel", r.cancelExecutionByIDWeb)
 	web.GET("/execution/:id/download", r.downloadLogs)
@@ -101,10 +114,22 @@ func (r *BlueBerry) setupAPIRoutes(api *echo.Group) {
 	}
 
 	api.GET("/tasks", r.getTasks)
+	api.POST("/task/:name/schedules", r.createTaskSchedule)
+	api.GET("/schedule/preview", r.previewSchedule)
+	api.GET("/task/:name/schedules/:id", r.getTaskSchedule)
+	api.PUT("/task/:name/schedules/:id", r.updateTaskSchedule)
+	api.DELETE("/task/:name/schedules/:id", r.deleteTaskSchedule)
+	api.POST("/task/:name/schedules/:id/pause", r.pauseTaskSchedule)
+	api.POST("/task/:name/schedules/:id/resume", r.resumeTaskSchedule)
 	api.GET("/task/:name/executions", r.getTaskExecutions)
 	api.GET("/task_run/:id/logs", r.getTaskRunLogs)
+	api.GET("/execution/:id", r.getExecution)
 	api.POST("/execution/:id/cancel", r.cancelExecutionByID)
 	api.POST("/task/:name/execute", r.executeTaskByName)
+	api.GET("/maintenance", r.getMaintenanceMode)
+	api.PUT("/maintenance", r.setMaintenanceMode)
+	api.GET("/calendars", r.getCalendars)
+	api.GET("/queue", r.getQueue)
 }
 
 // @title BlueBerry API
@@ -114,6 +139,7 @@ func (r *BlueBerry) setupAPIRoutes(api *echo.Group) {
 // @securityDefinitions.apiKey ApiKeyAuth
 // @in query
 // @name api_key
+
 // RunAPI starts the API server
 // @Summary Start API server
 // @Description Start the API server to manage tasks and schedules
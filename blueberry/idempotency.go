@@ -0,0 +1,81 @@
+package blueberry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultIdempotencyWindow is used when Options.IdempotencyWindow is not set
+const defaultIdempotencyWindow = 24 * time.Hour
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted, the stores index the key
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLockKey identifies the runs of a task started with the same idempotency key
+type idempotencyLockKey struct {
+	taskName string
+	key      string
+}
+
+// idempotencyLock serializes the runs of a task started with the same idempotency key
+type idempotencyLock struct {
+	mu   sync.Mutex
+	refs int // Callers holding or waiting for mu, the lock is dropped once none are left
+}
+
+// validateIdempotencyKey rejects keys the stores can not hold
+func validateIdempotencyKey(key string) error {
+	if utf8.RuneCountInString(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key must not be longer than %d characters", maxIdempotencyKeyLength)
+	}
+	return nil
+}
+
+func (r *BlueBerry) idempotencyWindow() time.Duration {
+	if r.options.IdempotencyWindow > 0 {
+		return r.options.IdempotencyWindow
+	}
+	return defaultIdempotencyWindow
+}
+
+// idempotentRun returns the ID of the run of the task stored with the idempotency key within the idempotency
+// window, 0 if a new run has to be started. The caller holds the lock of the key.
+func (t *Task) idempotentRun(key string) (int, error) {
+	taskRun, err := t.blueBerry.db.GetTaskRunByIdempotencyKey(context.Background(), t.name, key)
+	if err != nil {
+		return 0, fmt.Errorf("unable to look up idempotency key: %w", err)
+	}
+	if taskRun == nil || time.Since(taskRun.StartTime) > t.blueBerry.idempotencyWindow() {
+		return 0, nil
+	}
+	return taskRun.ID, nil
+}
+
+// lockIdempotencyKey locks the idempotency key of the task and returns the function unlocking it. Runs with other
+// keys or of other tasks do not wait for the lock.
+func (r *BlueBerry) lockIdempotencyKey(taskName, key string) func() {
+	lockKey := idempotencyLockKey{taskName: taskName, key: key}
+
+	r.idempotencyMux.Lock()
+	lock, ok := r.idempotencyLocks[lockKey]
+	if !ok {
+		lock = &idempotencyLock{}
+		r.idempotencyLocks[lockKey] = lock
+	}
+	lock.refs++
+	r.idempotencyMux.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		r.idempotencyMux.Lock()
+		defer r.idempotencyMux.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(r.idempotencyLocks, lockKey)
+		}
+	}
+}
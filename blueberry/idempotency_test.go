@@ -0,0 +1,124 @@
+package blueberry
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		firstKey  string
+		repeatKey string
+		window    time.Duration
+		wantSame  bool // The repeat returns the run of the first request
+		wantErr   bool // The repeat is rejected
+	}{
+		{"no key starts another run", "", "", 0, false, false},
+		{"same key returns the first run", "import-1", "import-1", 0, true, false},
+		{"other key starts another run", "import-1", "import-2", 0, false, false},
+		{"key outside the window starts another run", "import-1", "import-1", time.Nanosecond, false, false},
+		{"longest key is accepted", strings.Repeat("k", maxIdempotencyKeyLength), strings.Repeat("k", maxIdempotencyKeyLength), 0, true, false},
+		{"longer key is rejected", "import-1", strings.Repeat("k", maxIdempotencyKeyLength+1), 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := newTestInstance(t, newMemoryDB(), Options{IdempotencyWindow: tt.window})
+			task, _ := rb.RegisterTask("import", (&runCounter{}).task, versionSchema)
+
+			first, err := task.ExecuteNowWithOptions(TaskParams{"version": "v1"}, ExecuteOptions{IdempotencyKey: tt.firstKey})
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(time.Millisecond)
+			repeat, err := task.ExecuteNowWithOptions(TaskParams{"version": "v1"}, ExecuteOptions{IdempotencyKey: tt.repeatKey})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("repeat returned %v, want an error: %v", err, tt.wantErr)
+			}
+			if got := repeat == first; !tt.wantErr && got != tt.wantSame {
+				t.Errorf("repeat got run %d, first request got run %d", repeat, first)
+			}
+		})
+	}
+}
+
+func TestConcurrentRequestsWithSameKeyStartOneRun(t *testing.T) {
+	rb := newTestInstance(t, newMemoryDB(), Options{})
+	task, _ := rb.RegisterTask("import", (&runCounter{}).task, versionSchema)
+
+	var wg sync.WaitGroup
+	runIDs := make([]int, 20)
+	for i := range runIDs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			runIDs[i], _ = task.ExecuteNowWithOptions(TaskParams{"version": "v1"}, ExecuteOptions{IdempotencyKey: "import-1"})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, runID := range runIDs {
+		if runID == 0 || runID != runIDs[0] {
+			t.Fatalf("requests got runs %v, want a single run", runIDs)
+		}
+	}
+	if len(rb.idempotencyLocks) != 0 {
+		t.Errorf("%d idempotency locks left after the requests", len(rb.idempotencyLocks))
+	}
+}
+
+func TestIdempotencyLockOnlyBlocksSameKey(t *testing.T) {
+	rb := newTestInstance(t, newMemoryDB(), Options{})
+	unlock := rb.lockIdempotencyKey("import", "import-1")
+	defer unlock()
+
+	locked := make(chan struct{})
+	go func() {
+		rb.lockIdempotencyKey("import", "import-2")()
+		rb.lockIdempotencyKey("export", "import-1")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("other keys waited for the lock of import-1")
+	}
+}
+
+func TestIdempotencyKeyOfReusedRun(t *testing.T) {
+	db := newMemoryDB()
+	rb := newTestInstance(t, db, Options{})
+	release := make(chan struct{})
+	task, err := rb.RegisterTaskWithOptions("import", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}, versionSchema, TaskOptions{Unique: UniqueReuse})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := task.ExecuteNow(TaskParams{"version": "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reused, err := task.ExecuteNowWithOptions(TaskParams{"version": "v1"}, ExecuteOptions{IdempotencyKey: "import-1"})
+	if err != nil || reused != running {
+		t.Fatalf("request got run %d (%v), want the running run %d", reused, err, running)
+	}
+
+	// Once the reused run has finished, a repeat of the request still gets it instead of starting another run
+	close(release)
+	if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("import", "completed")) == 1 }) {
+		t.Fatal("reused run did not complete")
+	}
+	repeat, err := task.ExecuteNowWithOptions(TaskParams{"version": "v1"}, ExecuteOptions{IdempotencyKey: "import-1"})
+	if err != nil || repeat != running {
+		t.Errorf("repeat got run %d (%v), want the reused run %d", repeat, err, running)
+	}
+}
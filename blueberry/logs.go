@@ -3,6 +3,7 @@ package blueberry
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/gommon/log"
@@ -11,9 +12,18 @@ import (
 type Logger struct {
 	taskRun *TaskRun
 	db      DB
+	ended   atomic.Bool // Set once the run ended while its task function is still running
+}
+
+// end drops the entries logged from now on, they would be added to a run that already ended
+func (l *Logger) end() {
+	l.ended.Store(true)
 }
 
 func (l *Logger) log(level, message string) error {
+	if l.ended.Load() {
+		return fmt.Errorf("run %d has already ended, dropping the log entry", l.taskRun.ID)
+	}
 	logEntry := &TaskRunLog{
 		TaskRunID: l.taskRun.ID,
 		Timestamp: time.Now().UTC(),
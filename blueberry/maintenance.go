@@ -0,0 +1,22 @@
+package blueberry
+
+import "github.com/labstack/gommon/log"
+
+// SetMaintenanceMode suspends (or restores) all cron-triggered runs. Manual runs through
+// ExecuteNow, the web UI and the API are still allowed while maintenance mode is enabled.
+func (r *BlueBerry) SetMaintenanceMode(enabled bool) {
+	r.maintenanceMux.Lock()
+	defer r.maintenanceMux.Unlock()
+
+	if r.maintenance != enabled {
+		log.Infof("maintenance mode enabled: %t", enabled)
+	}
+	r.maintenance = enabled
+}
+
+// IsMaintenanceMode reports whether scheduled triggers are currently suspended
+func (r *BlueBerry) IsMaintenanceMode() bool {
+	r.maintenanceMux.RLock()
+	defer r.maintenanceMux.RUnlock()
+	return r.maintenance
+}
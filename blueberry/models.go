@@ -2,18 +2,33 @@ package blueberry
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 )
 
 type TaskFunc func(context.Context, TaskParams, *Logger) error
 
+// ResultTaskFunc is a task function that returns a result, which is stored as JSON with the run
+type ResultTaskFunc func(context.Context, TaskParams, *Logger) (any, error)
+
 type TaskRun struct {
 	ID        int                    `json:"id"`
 	TaskName  string                 `json:"task_name"`
 	StartTime time.Time              `json:"start_time"`
 	EndTime   time.Time              `json:"end_time"`
 	Params    map[string]interface{} `json:"params"`
-	Status    string                 `json:"status"` // "started", "completed", "failed", "cancelled"
+	Status    string                 `json:"status"`   // "pending", "queued", "started", "completed", "failed", "timed_out", "panicked", "cancelled", "abandoned", "skipped"
+	CatchUp   bool                   `json:"catch_up"` // Started on start up for a trigger missed while the process was down
+	RunAt     time.Time              `json:"run_at"`   // Time a delayed run was requested for, zero for runs started right away
+	Attempt   int                    `json:"attempt"`  // 1 for the first run, counting up for retries of a failed run
+	RetryOf   int                    `json:"retry_of"` // ID of the first attempt when the run is a retry, 0 otherwise
+	Priority  int                    `json:"priority"` // Queued runs with a higher priority start first
+
+	ScheduleID int           `json:"schedule_id"` // Schedule that triggered the run, 0 for manual runs
+	Timeout    time.Duration `json:"timeout"`     // Timeout the run was started with, 0 means no timeout
+
+	IdempotencyKey string          `json:"idempotency_key,omitempty"` // Key of the manual run request, repeats return this run
+	Result         json.RawMessage `json:"result,omitempty"`          // JSON encoded result of a ResultTaskFunc
 }
 
 // TaskRunLog represents a log entry for a task run
@@ -30,10 +45,16 @@ type DB interface {
 	SaveTaskRun(ctx context.Context, taskRun *TaskRun) error
 	SaveTaskRunLog(ctx context.Context, taskRunLog *TaskRunLog) error
 	GetTaskRuns(ctx context.Context) ([]TaskRun, error)
+	GetTaskRunsByStatus(ctx context.Context, status string) ([]TaskRun, error)
 	GetTaskRunByID(ctx context.Context, id int) (*TaskRun, error)
+	GetTaskRunByIdempotencyKey(ctx context.Context, taskName, key string) (*TaskRun, error)
+	SaveIdempotencyKey(ctx context.Context, taskName, key string, taskRunID int) error
 	GetTaskRunLogs(ctx context.Context, taskRunID int) ([]TaskRunLog, error)
 	GetPaginatedTaskRunLogs(ctx context.Context, taskRunID int, level string, page, size int) ([]TaskRunLog, int, error)
 	GetPaginatedTaskRunsForTaskName(ctx context.Context, name string, page, limit int) ([]TaskRun, error)
 	GetTaskRunsCountForTaskName(ctx context.Context, name string) (int, error)
+	SaveSchedule(ctx context.Context, schedule *ScheduleInfo) error
+	GetSchedules(ctx context.Context) ([]ScheduleInfo, error)
+	DeleteSchedule(ctx context.Context, id int) error
 	Close() error
 }
@@ -0,0 +1,45 @@
+package blueberry
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a run whose task function panicked
+type PanicError struct {
+	TaskName string
+	RunID    int
+	Value    any    // Value passed to panic
+	Stack    []byte // Stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task %s panicked in run %d: %v", e.TaskName, e.RunID, e.Value)
+}
+
+// callTaskFunc runs the task function and turns a panic into a *PanicError, so that a panicking task does not
+// take down the process
+func (t *Task) callTaskFunc(taskRun *TaskRun, call func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &PanicError{
+				TaskName: t.name,
+				RunID:    taskRun.ID,
+				Value:    recovered,
+				Stack:    debug.Stack(),
+			}
+		}
+	}()
+	return call()
+}
+
+// reportPanic hands a recovered panic to the OnPanic hook of the instance, a panicking hook is ignored
+func (r *BlueBerry) reportPanic(panicErr *PanicError) {
+	if r.options.OnPanic == nil {
+		return
+	}
+	defer func() {
+		_ = recover()
+	}()
+	r.options.OnPanic(panicErr)
+}
@@ -0,0 +1,110 @@
+package blueberry
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPanicRecovery(t *testing.T) {
+	tests := []struct {
+		name       string
+		onPanic    func(reported chan<- *PanicError) func(*PanicError)
+		wantReport bool
+	}{
+		{"without hook", nil, false},
+		{"hook gets the panic", func(reported chan<- *PanicError) func(*PanicError) {
+			return func(err *PanicError) { reported <- err }
+		}, true},
+		{"panicking hook is ignored", func(reported chan<- *PanicError) func(*PanicError) {
+			return func(err *PanicError) {
+				reported <- err
+				panic("tracker is down")
+			}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reported := make(chan *PanicError, 1)
+			var opts Options
+			if tt.onPanic != nil {
+				opts.OnPanic = tt.onPanic(reported)
+			}
+			db := newMemoryDB()
+			rb := newTestInstance(t, db, opts)
+			task, _ := rb.RegisterTask("import", func(context.Context, TaskParams, *Logger) error {
+				panic("lost the connection")
+			}, versionSchema)
+
+			runID, err := task.ExecuteNow(TaskParams{"version": "v1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("import", "panicked")) == 1 }) {
+				t.Fatal("run did not end as panicked")
+			}
+			logs, _ := db.GetTaskRunLogs(context.Background(), runID)
+			if len(logs) == 0 || !strings.Contains(logs[0].Message, "lost the connection") || !strings.Contains(logs[0].Message, "goroutine") {
+				t.Errorf("logs = %+v, want the panic value and the stack trace", logs)
+			}
+
+			select {
+			case err := <-reported:
+				if !tt.wantReport {
+					t.Fatalf("unexpected report %v", err)
+				}
+				if err.TaskName != "import" || err.RunID != runID || err.Value != "lost the connection" {
+					t.Errorf("reported %+v, want the panic of run %d", err, runID)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantReport {
+					t.Error("panic was not reported")
+				}
+			}
+		})
+	}
+}
+
+func TestPanicAfterTimeoutIsReported(t *testing.T) {
+	reported := make(chan *PanicError, 1)
+	db := newMemoryDB()
+	rb := newTestInstance(t, db, Options{OnPanic: func(err *PanicError) { reported <- err }})
+	release := make(chan struct{})
+	task, err := rb.RegisterTaskWithOptions("hang", func(context.Context, TaskParams, *Logger) error {
+		<-release // Ignores its context
+		panic("lost the connection")
+	}, versionSchema, TaskOptions{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runID, err := task.ExecuteNow(TaskParams{"version": "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("hang", "timed_out")) == 1 }) {
+		t.Fatal("run did not time out")
+	}
+	close(release)
+
+	select {
+	case err := <-reported:
+		if err.RunID != runID || err.Value != "lost the connection" {
+			t.Errorf("reported %+v, want the panic of run %d", err, runID)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("panic after the timeout was not reported")
+	}
+	logs, _ := db.GetTaskRunLogs(context.Background(), runID)
+	found := false
+	for _, entry := range logs {
+		found = found || strings.Contains(entry.Message, "panicked after it timed out")
+	}
+	if !found {
+		t.Errorf("logs = %+v, want the panic after the timeout", logs)
+	}
+	if taskRun, _ := db.GetTaskRunByID(context.Background(), runID); taskRun.Status != "timed_out" {
+		t.Errorf("run has status %s, want it to stay timed_out", taskRun.Status)
+	}
+}
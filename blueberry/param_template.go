@@ -0,0 +1,155 @@
+package blueberry
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// maxParamTemplateLength caps the length of a parameter holding a template, schedules can be saved through the API
+const maxParamTemplateLength = 1024
+
+// ParamTemplateData is available to template expressions in the string parameters of a schedule, e.g.
+// {{ .ScheduledTime | addDays -1 | date "2006-01-02" }} for the day before the trigger.
+type ParamTemplateData struct {
+	ScheduledTime time.Time // Time the trigger was due, in the zone the schedule is evaluated in
+	ScheduleID    int
+	TaskName      string
+}
+
+// paramTemplateFields are the fields of ParamTemplateData that templates may use
+var paramTemplateFields = map[string]bool{
+	"ScheduledTime": true,
+	"ScheduleID":    true,
+	"TaskName":      true,
+}
+
+// paramTemplateFuncs are the functions available to parameter templates. Time arguments come last so that
+// they can be used in pipelines.
+var paramTemplateFuncs = template.FuncMap{
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"addDate": func(years, months, days int, t time.Time) time.Time {
+		return t.AddDate(years, months, days)
+	},
+	"addDays": func(days int, t time.Time) time.Time {
+		return t.AddDate(0, 0, days)
+	},
+	"add": func(duration string, t time.Time) (time.Time, error) {
+		d, err := time.ParseDuration(duration)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Add(d), nil
+	},
+	"truncate": func(duration string, t time.Time) (time.Time, error) {
+		d, err := time.ParseDuration(duration)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Truncate(d), nil
+	},
+	"unix": func(t time.Time) int64 {
+		return t.Unix()
+	},
+}
+
+// isParamTemplate reports whether a parameter value holds a template expression
+func isParamTemplate(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.Contains(s, "{{")
+}
+
+// renderParams renders the template expressions in the string parameters of a schedule for a trigger due at
+// firedAt. Parameters without templates are copied as they are.
+func (r *BlueBerry) renderParams(scheduleInfo ScheduleInfo, firedAt time.Time) (TaskParams, error) {
+	params := TaskParams(scheduleInfo.Params)
+	data := ParamTemplateData{
+		ScheduledTime: firedAt.In(r.scheduleLocation(scheduleInfo)),
+		ScheduleID:    scheduleInfo.ID,
+		TaskName:      scheduleInfo.TaskName,
+	}
+
+	rendered := make(TaskParams, len(params))
+	for key, value := range params {
+		if !isParamTemplate(value) {
+			rendered[key] = value
+			continue
+		}
+
+		if len(value.(string)) > maxParamTemplateLength {
+			return nil, fmt.Errorf("template in parameter %s is longer than %d characters", key, maxParamTemplateLength)
+		}
+		tmpl, err := template.New(key).Funcs(paramTemplateFuncs).Option("missingkey=error").Parse(value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in parameter %s: %v", key, err)
+		}
+		if len(tmpl.Templates()) > 1 {
+			return nil, fmt.Errorf("invalid template in parameter %s: template definitions are not supported", key)
+		}
+		if err := checkParamTemplate(tmpl.Tree.Root); err != nil {
+			return nil, fmt.Errorf("invalid template in parameter %s: %v", key, err)
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, data); err != nil {
+			return nil, fmt.Errorf("unable to render parameter %s: %v", key, err)
+		}
+		rendered[key] = out.String()
+	}
+	return rendered, nil
+}
+
+// checkParamTemplate allows the documented fields and functions in plain actions and pipelines only. Conditions,
+// loops, variables, nested templates, methods of the data and the builtin functions of text/template are rejected.
+func checkParamTemplate(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			if err := checkParamTemplate(child); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkParamTemplate(n.Pipe)
+	case *parse.PipeNode:
+		if len(n.Decl) > 0 {
+			return fmt.Errorf("variables are not supported")
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkParamTemplate(cmd); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkParamTemplate(arg); err != nil {
+				return err
+			}
+		}
+	case *parse.IdentifierNode:
+		if _, ok := paramTemplateFuncs[n.Ident]; !ok {
+			return fmt.Errorf("function %s is not supported", n.Ident)
+		}
+	case *parse.FieldNode:
+		if len(n.Ident) != 1 || !paramTemplateFields[n.Ident[0]] {
+			return fmt.Errorf("field %s is not supported", n)
+		}
+	case *parse.TextNode, *parse.StringNode, *parse.NumberNode, *parse.BoolNode, *parse.DotNode:
+		// Text and constants are allowed as they are
+	default:
+		return fmt.Errorf("%s is not supported", n)
+	}
+	return nil
+}
+
+// validateParamTemplates renders the parameter templates of a schedule once so that mistakes are reported when
+// the schedule is saved rather than on its first trigger
+func (r *BlueBerry) validateParamTemplates(scheduleInfo ScheduleInfo) error {
+	if _, err := r.renderParams(scheduleInfo, time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	return nil
+}
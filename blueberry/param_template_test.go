@@ -0,0 +1,51 @@
+package blueberry
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string // Part of the error, empty when rendering succeeds
+	}{
+		{"plain value", "report", "report", ""},
+		{"date of the day before", `{{ .ScheduledTime | addDays -1 | date "2006-01-02" }}`, "2026-10-15", ""},
+		{"shifted and truncated time", `{{ .ScheduledTime | add "-6h" | truncate "24h" | date "2006-01-02 15:04" }}`, "2026-10-15 00:00", ""},
+		{"schedule and task", `{{ .TaskName }}-{{ .ScheduleID }}`, "report-7", ""},
+		{"unix timestamp", `{{ unix .ScheduledTime }}`, "1792127400", ""},
+		{"unknown field", `{{ .Secret }}`, "", "field .Secret is not supported"},
+		{"method of a field", `{{ .ScheduledTime.Year }}`, "", "field .ScheduledTime.Year is not supported"},
+		{"builtin function", `{{ printf "%s" .TaskName }}`, "", "function printf is not supported"},
+		{"condition", `{{ if .TaskName }}x{{ end }}`, "", "is not supported"},
+		{"loop", `{{ range .TaskName }}x{{ end }}`, "", "is not supported"},
+		{"variable", `{{ $t := .ScheduledTime }}`, "", "variables are not supported"},
+		{"template definition", `{{ define "x" }}x{{ end }}`, "", "template definitions are not supported"},
+		{"too long", "{{ .TaskName }}" + strings.Repeat("x", maxParamTemplateLength), "", "longer than"},
+		{"syntax error", `{{ .TaskName `, "", "invalid template"},
+	}
+	rb := NewBlueBerryInstanceWithOptions(newMemoryDB(), Options{Location: time.UTC})
+	firedAt := time.Date(2026, 10, 16, 5, 10, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduleInfo := ScheduleInfo{ID: 7, TaskName: "report", Params: map[string]interface{}{"value": tt.template}}
+			params, err := rb.renderParams(scheduleInfo, firedAt)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := params["value"]; got != tt.want {
+				t.Errorf("rendered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package blueberry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPreviewRuns caps the number of firing times returned by PreviewSchedule
+const maxPreviewRuns = 100
+
+// SchedulePreview lists the upcoming firing times of a cron expression
+type SchedulePreview struct {
+	Schedule    string      `json:"schedule"`
+	Timezone    string      `json:"timezone"`
+	Description string      `json:"description"`
+	Next        []time.Time `json:"next"`
+}
+
+// PreviewSchedule returns the next n firing times of a cron expression, evaluated in the given IANA time zone
+// (empty for the default location), together with a human-readable description of the expression.
+// Nothing is registered, so it can be used to check an expression before saving a schedule.
+func (r *BlueBerry) PreviewSchedule(expr string, n int, tz string) (SchedulePreview, error) {
+	if n < 1 || n > maxPreviewRuns {
+		return SchedulePreview{}, fmt.Errorf("%w: the number of runs must be between 1 and %d", ErrInvalidSchedule, maxPreviewRuns)
+	}
+
+	scheduleInfo := ScheduleInfo{Schedule: expr, ScheduleOptions: ScheduleOptions{Timezone: tz}}
+	parsed, err := r.parseScheduleWithOptions(expr, scheduleInfo.ScheduleOptions)
+	if err != nil {
+		return SchedulePreview{}, err
+	}
+
+	location := r.scheduleLocation(scheduleInfo)
+	preview := SchedulePreview{
+		Schedule:    expr,
+		Timezone:    location.String(),
+		Description: describeSchedule(expr),
+		Next:        make([]time.Time, 0, n),
+	}
+	next := time.Now().In(location)
+	for len(preview.Next) < n {
+		next = parsed.Next(next)
+		if next.IsZero() {
+			break
+		}
+		preview.Next = append(preview.Next, next.In(location))
+	}
+	return preview, nil
+}
+
+// describeSchedule returns a human-readable description of a cron expression, e.g. "At 12:00 on Monday".
+// Expressions it can not describe are returned as they are.
+func describeSchedule(expr string) string {
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		if i := strings.IndexByte(spec, ' '); i > 0 {
+			spec = strings.TrimSpace(spec[i:])
+		}
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		switch spec {
+		case "@yearly", "@annually":
+			return "At 00:00 on January 1"
+		case "@monthly":
+			return "At 00:00 on day 1 of the month"
+		case "@weekly":
+			return "At 00:00 on Sunday"
+		case "@daily", "@midnight":
+			return "At 00:00 every day"
+		case "@hourly":
+			return "At minute 0 of every hour"
+		}
+		every := strings.TrimSpace(strings.TrimPrefix(spec, "@every"))
+		if _, err := time.ParseDuration(every); err == nil {
+			return "Every " + every
+		}
+		return expr
+	}
+
+	fields := strings.Fields(spec)
+	second := "0"
+	switch len(fields) {
+	case 5:
+	case 6:
+		second, fields = fields[0], fields[1:]
+	default:
+		return expr
+	}
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	// prefix joins a preposition to a fixed value, steps such as "every 3 months" stand on their own
+	prefix := func(preposition, description string) string {
+		if strings.HasPrefix(description, "every ") {
+			return description
+		}
+		return preposition + " " + description
+	}
+
+	var parts []string
+	if isCronNumber(second) && isCronNumber(minute) && isCronNumber(hour) {
+		clock := fmt.Sprintf("at %02s:%02s", hour, minute)
+		if second != "0" {
+			clock += fmt.Sprintf(":%02s", second)
+		}
+		parts = append(parts, clock)
+	} else {
+		if second != "0" {
+			parts = append(parts, prefix("at", describeCronField(second, "second", nil)))
+			if minute == "*" && !strings.Contains(second, "*") {
+				parts = append(parts, "of every minute")
+			}
+		}
+		if minute != "*" || second == "0" {
+			parts = append(parts, prefix("at", describeCronField(minute, "minute", nil)))
+		}
+		if hour != "*" {
+			parts = append(parts, "past "+describeCronField(hour, "hour", nil))
+		} else if minute != "*" && !strings.Contains(minute, "*") {
+			parts = append(parts, "of every hour")
+		}
+	}
+
+	var days []string
+	if dom != "*" && dom != "?" {
+		days = append(days, prefix("on", describeCronField(dom, "day", nil))+" of the month")
+	}
+	if dow != "*" && dow != "?" {
+		days = append(days, prefix("on", describeCronField(dow, "day", cronWeekdays)))
+	}
+	if len(days) > 0 {
+		parts = append(parts, strings.Join(days, " or "))
+	}
+	if month != "*" && month != "?" {
+		parts = append(parts, prefix("in", describeCronField(month, "month", cronMonths)))
+	} else if len(days) == 0 && strings.HasPrefix(parts[0], "at ") && hour != "*" && !strings.Contains(hour, "*") {
+		parts = append(parts, "every day")
+	}
+
+	description := strings.Join(parts, " ")
+	return strings.ToUpper(description[:1]) + description[1:]
+}
+
+var cronWeekdays = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+var cronMonths = []string{"", "January", "February", "March", "April", "May", "June", "July", "August",
+	"September", "October", "November", "December"}
+
+func isCronNumber(field string) bool {
+	_, err := strconv.Atoi(field)
+	return err == nil
+}
+
+// describeCronField describes a single cron field. With names, numbers and abbreviations such as MON or JAN are
+// translated into names and the unit is only used for steps.
+func describeCronField(field, unit string, names []string) string {
+	if field == "*" || field == "?" {
+		return "every " + unit
+	}
+
+	name := func(value string) string {
+		if names == nil {
+			return value
+		}
+		if i, err := strconv.Atoi(value); err == nil && i >= 0 && i < len(names) {
+			return names[i]
+		}
+		for _, n := range names {
+			if n != "" && strings.EqualFold(value, n[:3]) {
+				return n
+			}
+		}
+		return value
+	}
+
+	var items []string
+	for _, item := range strings.Split(field, ",") {
+		span, step, hasStep := strings.Cut(item, "/")
+		low, high, isRange := strings.Cut(span, "-")
+
+		var text string
+		switch {
+		case span == "*" || span == "?":
+			text = ""
+		case isRange:
+			text = name(low) + " through " + name(high)
+		default:
+			text = name(span)
+		}
+
+		if hasStep {
+			every := "every " + step + " " + unit + "s"
+			if text != "" {
+				every += " from " + text
+			}
+			items = append(items, every)
+			continue
+		}
+		items = append(items, text)
+	}
+
+	description := strings.Join(items, ", ")
+	if names != nil || strings.HasPrefix(description, "every ") {
+		return description
+	}
+	if len(items) > 1 || strings.Contains(description, " through ") {
+		return unit + "s " + description
+	}
+	return unit + " " + description
+}
@@ -0,0 +1,227 @@
+package blueberry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// queuedRun is a run waiting for a slot because the concurrency limits were reached
+type queuedRun struct {
+	task    *Task
+	taskRun *TaskRun
+	opts    runOptions
+	exec    *execution
+}
+
+// QueuedRun describes a run waiting for a slot. Position 1 is the next run to start once the limits allow it,
+// runs of tasks at their own limit are passed over until a run of the task finishes.
+type QueuedRun struct {
+	Position   int                    `json:"position"`
+	ID         int                    `json:"id"`
+	TaskName   string                 `json:"task_name"`
+	ScheduleID int                    `json:"schedule_id,omitempty"`
+	Priority   int                    `json:"priority"`
+	QueuedAt   time.Time              `json:"queued_at"`
+	Params     map[string]interface{} `json:"params"`
+}
+
+// canStart reports whether a run of the task fits into the concurrency limits. Runs of a schedule with the queue
+// overlap policy also wait for the other runs of their schedule. The caller holds queueMux.
+func (r *BlueBerry) canStart(task *Task, opts runOptions) bool {
+	if opts.overlapQueue && r.activeSchedules[opts.scheduleID] > 0 {
+		return false
+	}
+	if r.options.MaxConcurrentRuns > 0 && r.active >= r.options.MaxConcurrentRuns {
+		return false
+	}
+	return task.options.MaxConcurrency <= 0 || r.activeTasks[task.name] < task.options.MaxConcurrency
+}
+
+// takeSlot counts a run of the task and schedule against the concurrency limits, the caller holds queueMux
+func (r *BlueBerry) takeSlot(task *Task, scheduleID int) {
+	r.active++
+	r.activeTasks[task.name]++
+	if scheduleID != 0 {
+		r.activeSchedules[scheduleID]++
+	}
+}
+
+// acquireSlot takes a slot for a run of the task if the concurrency limits allow it. Queued runs never wait
+// for a slot a new run could take, so a new run does not overtake them.
+func (r *BlueBerry) acquireSlot(task *Task, opts runOptions) bool {
+	r.queueMux.Lock()
+	defer r.queueMux.Unlock()
+
+	if !r.canStart(task, opts) {
+		return false
+	}
+	r.takeSlot(task, opts.scheduleID)
+	return true
+}
+
+// releaseSlot frees the slot of a finished run of the task and schedule and starts the queued runs that fit in now
+func (r *BlueBerry) releaseSlot(task *Task, scheduleID int) {
+	r.queueMux.Lock()
+	r.active--
+	if r.activeTasks[task.name]--; r.activeTasks[task.name] <= 0 {
+		delete(r.activeTasks, task.name)
+	}
+	if scheduleID != 0 {
+		if r.activeSchedules[scheduleID]--; r.activeSchedules[scheduleID] <= 0 {
+			delete(r.activeSchedules, scheduleID)
+		}
+	}
+	r.queueMux.Unlock()
+
+	r.startQueuedRuns()
+}
+
+// queueRun stores the run with the "queued" status and adds it to the end of the queue
+func (r *BlueBerry) queueRun(task *Task, taskRun *TaskRun, opts runOptions, exec *execution) error {
+	// The start time holds the time the run was queued until it starts, restored runs keep theirs
+	if taskRun.Status != "queued" {
+		taskRun.StartTime = time.Now().UTC()
+		taskRun.Status = "queued"
+	}
+	if taskRun.Attempt == 0 {
+		taskRun.Attempt = 1
+	}
+	if err := r.db.SaveTaskRun(context.Background(), taskRun); err != nil {
+		return err
+	}
+	exec.runID = taskRun.ID
+
+	r.queueMux.Lock()
+	r.queue = append(r.queue, &queuedRun{task: task, taskRun: taskRun, opts: opts, exec: exec})
+	r.queueMux.Unlock()
+
+	// A slot may have been freed while the run was stored
+	r.startQueuedRuns()
+	return nil
+}
+
+// nextQueuedRun removes the queued run with the highest priority that fits into the concurrency limits from the
+// queue and takes a slot for it. Equal priorities start oldest first, runs of tasks at their own limit do not hold
+// up the runs of other tasks.
+func (r *BlueBerry) nextQueuedRun() *queuedRun {
+	r.queueMux.Lock()
+	defer r.queueMux.Unlock()
+
+	next := -1
+	for i, queued := range r.queue {
+		if !r.canStart(queued.task, queued.opts) {
+			continue
+		}
+		if next < 0 || queued.taskRun.Priority > r.queue[next].taskRun.Priority {
+			next = i
+		}
+	}
+	if next < 0 {
+		return nil
+	}
+
+	queued := r.queue[next]
+	r.queue = append(r.queue[:next], r.queue[next+1:]...)
+	r.takeSlot(queued.task, queued.opts.scheduleID)
+	return queued
+}
+
+// GetQueue returns the queued runs in the order they start in
+func (r *BlueBerry) GetQueue() []QueuedRun {
+	r.queueMux.Lock()
+	runs := make([]QueuedRun, 0, len(r.queue))
+	for _, queued := range r.queue {
+		runs = append(runs, QueuedRun{
+			ID:         queued.taskRun.ID,
+			TaskName:   queued.task.name,
+			ScheduleID: queued.exec.scheduleID,
+			Priority:   queued.taskRun.Priority,
+			QueuedAt:   queued.taskRun.StartTime,
+			Params:     queued.taskRun.Params,
+		})
+	}
+	r.queueMux.Unlock()
+
+	// The queue is kept oldest first, a stable sort keeps that order within a priority
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Priority > runs[j].Priority
+	})
+	for i := range runs {
+		runs[i].Position = i + 1
+	}
+	return runs
+}
+
+// startQueuedRuns starts queued runs for as long as the concurrency limits allow
+func (r *BlueBerry) startQueuedRuns() {
+	for queued := r.nextQueuedRun(); queued != nil; queued = r.nextQueuedRun() {
+		if err := queued.task.start(queued.taskRun, queued.opts, queued.exec); err != nil {
+			log.Errorf("unable to start queued run %d of task %s: %v", queued.taskRun.ID, queued.task.name, err)
+			close(queued.exec.done)
+			r.releaseSlot(queued.task, queued.opts.scheduleID)
+		}
+	}
+}
+
+// cancelQueuedRun removes a run from the queue, it reports whether the run was still queued
+func (r *BlueBerry) cancelQueuedRun(runID int) bool {
+	r.queueMux.Lock()
+	defer r.queueMux.Unlock()
+
+	for i, queued := range r.queue {
+		if queued.taskRun.ID != runID {
+			continue
+		}
+		r.queue = append(r.queue[:i], r.queue[i+1:]...)
+		close(queued.exec.done)
+		return true
+	}
+	return false
+}
+
+// queuedForSchedule returns the queued runs triggered by the given schedule
+func (r *BlueBerry) queuedForSchedule(scheduleID int) []*execution {
+	r.queueMux.Lock()
+	defer r.queueMux.Unlock()
+
+	var queued []*execution
+	for _, run := range r.queue {
+		if run.exec.scheduleID == scheduleID {
+			queued = append(queued, run.exec)
+		}
+	}
+	return queued
+}
+
+// stopQueuedRuns empties the queue without changing the stored status of the runs
+func (r *BlueBerry) stopQueuedRuns() {
+	r.queueMux.Lock()
+	defer r.queueMux.Unlock()
+
+	r.queue = nil
+}
+
+// restoreQueuedRuns queues the runs that were still queued when the process stopped again, in their original order.
+// Like a pending run that becomes due, a restored run is skipped by the unique policy of the task when a run with the
+// same parameters is queued or running already.
+func (r *BlueBerry) restoreQueuedRuns() {
+	r.restoreRuns("queued", func(task *Task, taskRun *TaskRun) {
+		// Only the stored run survives a restart, it runs with the settings of the task
+		opts := restoredRunOptions(taskRun)
+		opts.overlapQueue = taskRun.RetryOf == 0 && task.queuesTriggers(taskRun.ScheduleID)
+		_, err := task.runUnique(taskRun, opts)
+		var alreadyRunning *AlreadyRunningError
+		if errors.As(err, &alreadyRunning) {
+			task.skipPendingRun(taskRun, fmt.Sprintf("Skipped after the restart: run %d with the same parameters is queued or running", alreadyRunning.RunID))
+			return
+		}
+		if err != nil {
+			log.Errorf("unable to restore queued run %d of task %s: %v", taskRun.ID, task.name, err)
+		}
+	})
+}
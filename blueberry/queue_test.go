@@ -0,0 +1,104 @@
+package blueberry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueueOrdersRunsByPriority(t *testing.T) {
+	zero, one, five := 0, 1, 5
+	tests := []struct {
+		name         string
+		taskPriority int
+		overrides    []*int // Priorities of the queued runs in the order they are queued
+		want         []int  // Priorities of the queue, next run first
+	}{
+		{"task priority applies without override", -1, []*int{nil, nil}, []int{-1, -1}},
+		{"zero overrides a negative task priority", -1, []*int{nil, &zero}, []int{0, -1}},
+		{"higher priorities start first", 0, []*int{&one, nil, &five}, []int{5, 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := newTestInstance(t, newMemoryDB(), Options{MaxConcurrentRuns: 1})
+			release := make(chan struct{})
+			defer close(release)
+			task, err := rb.RegisterTaskWithOptions("sync", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+				return nil
+			}, versionSchema, TaskOptions{Priority: tt.taskPriority})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The first run takes the only slot, the others are queued behind it
+			if _, err := task.ExecuteNow(TaskParams{"version": "blocking"}); err != nil {
+				t.Fatal(err)
+			}
+			for _, priority := range tt.overrides {
+				if _, err := task.ExecuteNowWithOptions(TaskParams{"version": "queued"}, ExecuteOptions{Priority: priority}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			queue := rb.GetQueue()
+			if len(queue) != len(tt.want) {
+				t.Fatalf("queue = %+v, want %d runs", queue, len(tt.want))
+			}
+			for i, queued := range queue {
+				if queued.Position != i+1 || queued.Priority != tt.want[i] {
+					t.Errorf("queue[%d] has position %d and priority %d, want %d and %d", i, queued.Position, queued.Priority, i+1, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSchedulePriorityOverridesTask(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name     string
+		priority *int
+		want     int
+	}{
+		{"task priority applies without override", nil, 5},
+		{"zero overrides the task priority", &zero, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := newTestInstance(t, newMemoryDB(), Options{MaxConcurrentRuns: 1})
+			release := make(chan struct{})
+			defer close(release)
+			task, err := rb.RegisterTaskWithOptions("sync", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+				return nil
+			}, versionSchema, TaskOptions{Priority: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+			scheduleInfo, err := task.CreateScheduleWithOptions(TaskParams{"version": "scheduled"}, "0 0 1 1 *", ScheduleOptions{Priority: tt.priority})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored, _ := task.GetSchedule(scheduleInfo.ID); (stored.Priority == nil) != (tt.priority == nil) {
+				t.Fatalf("stored priority = %v, want %v", stored.Priority, tt.priority)
+			}
+
+			if _, err := task.ExecuteNow(TaskParams{"version": "blocking"}); err != nil {
+				t.Fatal(err)
+			}
+			task.fireSchedule(scheduleInfo, time.Now(), false)
+
+			queue := rb.GetQueue()
+			if len(queue) != 1 || queue[0].Priority != tt.want {
+				t.Fatalf("queue = %+v, want a run with priority %d", queue, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package blueberry_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ersauravadhikari/blueberry-go/blueberry"
+	"github.com/ersauravadhikari/blueberry-go/blueberry/store"
+)
+
+// stores opens the stores tested across restarts, every call opens the same store again
+var stores = []struct {
+	name string
+	open func(t *testing.T, dir string) blueberry.DB
+}{
+	{"sqlite", func(t *testing.T, dir string) blueberry.DB {
+		db, err := store.NewSQLiteDB(filepath.Join(dir, "blueberry.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}},
+	{"filesystem", func(t *testing.T, dir string) blueberry.DB {
+		db, err := store.NewFileStoreDB(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}},
+}
+
+var countSchema = blueberry.NewTaskSchema(blueberry.TaskParamDefinition{"count": blueberry.TypeInt})
+
+// recorder records the parameters of the runs of a task
+type recorder struct {
+	mu     sync.Mutex
+	counts []int
+}
+
+func (rec *recorder) task(_ context.Context, params blueberry.TaskParams, _ *blueberry.Logger) error {
+	count, err := params.GetInt("count")
+	if err != nil {
+		return err
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.counts = append(rec.counts, count)
+	return nil
+}
+
+func (rec *recorder) runs() []int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]int(nil), rec.counts...)
+}
+
+func waitForStatus(t *testing.T, db blueberry.DB, runID int, status string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		taskRun, err := db.GetTaskRunByID(context.Background(), runID)
+		if err == nil && taskRun.Status == status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run %d did not reach status %s: %+v, %v", runID, status, taskRun, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPendingRunsSurviveRestart(t *testing.T) {
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			db := s.open(t, dir)
+			first := blueberry.NewBlueBerryInstance(db)
+			task, err := first.RegisterTask("count", (&recorder{}).task, countSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runID, err := task.ExecuteAfter(blueberry.TaskParams{"count": 7}, 200*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first.Shutdown()
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			db = s.open(t, dir)
+			defer db.Close()
+			rec := &recorder{}
+			second := blueberry.NewBlueBerryInstance(db)
+			defer second.Shutdown()
+			if _, err := second.RegisterTask("count", rec.task, countSchema); err != nil {
+				t.Fatal(err)
+			}
+			pending, err := db.GetTaskRunsByStatus(context.Background(), "pending")
+			if err != nil || len(pending) != 1 || pending[0].ID != runID {
+				t.Fatalf("pending runs before start = %+v, %v", pending, err)
+			}
+			if err := second.InitTaskScheduler(); err != nil {
+				t.Fatal(err)
+			}
+
+			waitForStatus(t, db, runID, "completed")
+			if got := rec.runs(); len(got) != 1 || got[0] != 7 {
+				t.Errorf("restored run saw counts %v, want [7]", got)
+			}
+			if pending, _ := db.GetTaskRunsByStatus(context.Background(), "pending"); len(pending) != 0 {
+				t.Errorf("runs still pending after the restart: %+v", pending)
+			}
+		})
+	}
+}
+
+func TestQueuedRunsSurviveRestart(t *testing.T) {
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			db := s.open(t, dir)
+			first := blueberry.NewBlueBerryInstanceWithOptions(db, blueberry.Options{MaxConcurrentRuns: 1})
+			started, stopped := make(chan struct{}), make(chan struct{})
+			task, err := first.RegisterTask("count", func(ctx context.Context, _ blueberry.TaskParams, _ *blueberry.Logger) error {
+				defer close(stopped)
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			}, countSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := task.ExecuteNow(blueberry.TaskParams{"count": 1}); err != nil {
+				t.Fatal(err)
+			}
+			<-started
+			var queued []int
+			for _, count := range []int{2, 3} {
+				runID, err := task.ExecuteNow(blueberry.TaskParams{"count": count})
+				if err != nil {
+					t.Fatal(err)
+				}
+				queued = append(queued, runID)
+			}
+			first.Shutdown()
+			// Let the cancelled run store its end before the store is closed
+			<-stopped
+			time.Sleep(50 * time.Millisecond)
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			db = s.open(t, dir)
+			defer db.Close()
+			rec := &recorder{}
+			second := blueberry.NewBlueBerryInstanceWithOptions(db, blueberry.Options{MaxConcurrentRuns: 1})
+			defer second.Shutdown()
+			if _, err := second.RegisterTask("count", rec.task, countSchema); err != nil {
+				t.Fatal(err)
+			}
+			if err := second.InitTaskScheduler(); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, runID := range queued {
+				waitForStatus(t, db, runID, "completed")
+			}
+			if got := rec.runs(); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+				t.Errorf("restored runs saw counts %v, want [2 3]", got)
+			}
+		})
+	}
+}
+
+func TestRestoredRunsKeepTheirOptions(t *testing.T) {
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			dir := t.TempDir()
+			blocking := func(ctx context.Context, _ blueberry.TaskParams, _ *blueberry.Logger) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+
+			db := s.open(t, dir)
+			first := blueberry.NewBlueBerryInstanceWithOptions(db, blueberry.Options{MaxConcurrentRuns: 1})
+			task, err := first.RegisterTask("wait", blocking, countSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := task.ExecuteNow(blueberry.TaskParams{"count": 1}); err != nil {
+				t.Fatal(err)
+			}
+			priority := 0
+			runID, err := task.ExecuteNowWithOptions(blueberry.TaskParams{"count": 2}, blueberry.ExecuteOptions{Timeout: 100 * time.Millisecond, Priority: &priority})
+			if err != nil {
+				t.Fatal(err)
+			}
+			first.Shutdown()
+			time.Sleep(50 * time.Millisecond)
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The task has no timeout of its own and a lower priority, the restored run keeps the ones it was started with
+			db = s.open(t, dir)
+			defer db.Close()
+			second := blueberry.NewBlueBerryInstance(db)
+			defer second.Shutdown()
+			if _, err := second.RegisterTaskWithOptions("wait", blocking, countSchema, blueberry.TaskOptions{Priority: -1}); err != nil {
+				t.Fatal(err)
+			}
+			if err := second.InitTaskScheduler(); err != nil {
+				t.Fatal(err)
+			}
+
+			waitForStatus(t, db, runID, "timed_out")
+			taskRun, err := db.GetTaskRunByID(context.Background(), runID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if taskRun.Timeout != 100*time.Millisecond || taskRun.Priority != 0 {
+				t.Errorf("restored run has timeout %s and priority %d, want 100ms and 0", taskRun.Timeout, taskRun.Priority)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeysSurviveRestart(t *testing.T) {
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			dir := t.TempDir()
+			db := s.open(t, dir)
+			var ids []int
+			for _, key := range []string{"import-1", "import-2", "import-1", ""} {
+				taskRun := &blueberry.TaskRun{TaskName: "import", StartTime: time.Now().UTC(), Status: "completed", IdempotencyKey: key}
+				if err := db.SaveTaskRun(context.Background(), taskRun); err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, taskRun.ID)
+			}
+			// The last run was started without a key and reused for a request with one
+			if err := db.SaveIdempotencyKey(context.Background(), "import", "import-3", ids[3]); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			db = s.open(t, dir)
+			defer db.Close()
+			tests := []struct {
+				taskName, key string
+				want          int
+			}{
+				{"import", "import-1", ids[2]},
+				{"import", "import-2", ids[1]},
+				{"import", "import-3", ids[3]},
+				{"import", "import-4", 0},
+				{"export", "import-1", 0},
+			}
+			for _, tt := range tests {
+				taskRun, err := db.GetTaskRunByIdempotencyKey(context.Background(), tt.taskName, tt.key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := 0
+				if taskRun != nil {
+					got = taskRun.ID
+				}
+				if got != tt.want {
+					t.Errorf("run of task %s with key %s = %d, want %d", tt.taskName, tt.key, got, tt.want)
+				}
+			}
+		})
+	}
+}
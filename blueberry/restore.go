@@ -0,0 +1,55 @@
+package blueberry
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// restoreRuns calls restore for the runs stored with the given status, oldest first, when the process starts.
+// Runs of tasks that are not registered are left as they are. Stored parameters lose their Go types, validation
+// converts them back; runs whose parameters no longer fit the schema of the task are marked as failed.
+func (r *BlueBerry) restoreRuns(status string, restore func(task *Task, taskRun *TaskRun)) {
+	taskRuns, err := r.db.GetTaskRunsByStatus(context.Background(), status)
+	if err != nil {
+		log.Errorf("unable to load %s runs: %v", status, err)
+		return
+	}
+
+	for i := range taskRuns {
+		taskRun := &taskRuns[i]
+		task, ok := r.GetTask(taskRun.TaskName)
+		if !ok {
+			log.Warnf("skipping %s run %d: task %s is not registered", status, taskRun.ID, taskRun.TaskName)
+			continue
+		}
+
+		if err := task.ValidateParams(taskRun.Params); err != nil {
+			taskRun.Status = "failed"
+			taskRun.EndTime = time.Now().UTC()
+			if err := r.db.SaveTaskRun(context.Background(), taskRun); err != nil {
+				log.Errorf("unable to save %s run %d: %v", status, taskRun.ID, err)
+			}
+			logger := &Logger{taskRun: taskRun, db: r.db}
+			_ = logger.Errorf("The %s run can not be restored due to: %v", status, err)
+			continue
+		}
+
+		restore(task, taskRun)
+	}
+}
+
+// restoredRunOptions returns the options a stored run was started with, so that it runs with its own timeout and
+// priority and keeps counting for its schedule after a restart
+func restoredRunOptions(taskRun *TaskRun) runOptions {
+	priority := taskRun.Priority
+	return runOptions{
+		scheduleID: taskRun.ScheduleID,
+		catchUp:    taskRun.CatchUp,
+		attempt:    taskRun.Attempt,
+		retryOf:    taskRun.RetryOf,
+		timeout:    taskRun.Timeout,
+		priority:   &priority,
+	}
+}
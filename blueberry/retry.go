@@ -0,0 +1,97 @@
+package blueberry
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// RetryPolicy retries failed runs of a task with exponential backoff. Every retry is stored as a delayed run of
+// its own, linked to the original run through TaskRun.RetryOf.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first run. 0 or 1 disables retries.
+	MaxAttempts int
+
+	// InitialDelay is the wait before the first retry
+	InitialDelay time.Duration
+
+	// Multiplier grows the delay after every attempt. Defaults to 2.
+	Multiplier float64
+
+	// MaxDelay caps the delay between attempts. 0 means no cap.
+	MaxDelay time.Duration
+
+	// Retryable decides whether a failure is retried. When nil every error is retried.
+	Retryable func(error) bool
+
+	// RetryAbandoned also retries runs that were abandoned because the process stopped while they were running.
+	// They are retried on InitTaskScheduler with ErrRunAbandoned as the error.
+	RetryAbandoned bool
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 {
+		return errors.New("max attempts must not be negative")
+	}
+	if p.InitialDelay < 0 || p.MaxDelay < 0 {
+		return errors.New("retry delays must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return errors.New("retry multiplier must be at least 1")
+	}
+	return nil
+}
+
+// shouldRetry reports whether a run that failed with err on the given attempt gets another attempt
+func (p RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// backoff returns the delay before the attempt following the given one
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	if delay > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+// retry stores the next attempt of a failed run as a delayed run if the retry policy of the task allows it.
+// The attempt keeps the run options of the failed run.
+func (t *Task) retry(taskRun *TaskRun, opts runOptions, runErr error, logger *Logger) {
+	policy := t.options.Retry
+	if !policy.shouldRetry(taskRun.Attempt, runErr) {
+		return
+	}
+
+	original := taskRun.RetryOf
+	if original == 0 {
+		original = taskRun.ID
+	}
+
+	delay := policy.backoff(taskRun.Attempt)
+	opts.attempt = taskRun.Attempt + 1
+	opts.retryOf = original
+	// A retry is not another occurrence of the schedule and does not wait for its other runs
+	opts.overlapQueue = false
+	next, err := t.executeAt(taskRun.Params, time.Now().Add(delay), opts)
+	if err != nil {
+		log.Errorf("unable to retry run %d of task %s: %v", taskRun.ID, t.name, err)
+		_ = logger.Error("Unable to schedule a retry due to: " + err.Error())
+		return
+	}
+	_ = logger.Infof("Retrying as run %d in %s (attempt %d of %d)", next.ID, delay, next.Attempt, policy.MaxAttempts)
+}
@@ -0,0 +1,108 @@
+package blueberry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errPermanent = errors.New("permanent")
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"first retry waits the initial delay", RetryPolicy{InitialDelay: time.Second}, 1, time.Second},
+		{"delay doubles by default", RetryPolicy{InitialDelay: time.Second}, 3, 4 * time.Second},
+		{"custom multiplier", RetryPolicy{InitialDelay: time.Second, Multiplier: 3}, 3, 9 * time.Second},
+		{"delay is capped", RetryPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second}, 4, 5 * time.Second},
+		{"overflow is capped", RetryPolicy{InitialDelay: time.Hour}, 100, math.MaxInt64},
+		{"no delay", RetryPolicy{}, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.backoff(tt.attempt); got != tt.want {
+				t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	retryable := func(err error) bool { return !errors.Is(err, errPermanent) }
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		err     error
+		want    bool
+	}{
+		{"disabled", RetryPolicy{}, 1, errors.New("failed"), false},
+		{"attempts left", RetryPolicy{MaxAttempts: 3}, 2, errors.New("failed"), true},
+		{"attempts used up", RetryPolicy{MaxAttempts: 3}, 3, errors.New("failed"), false},
+		{"retryable error", RetryPolicy{MaxAttempts: 3, Retryable: retryable}, 1, errors.New("failed"), true},
+		{"error that is not retryable", RetryPolicy{MaxAttempts: 3, Retryable: retryable}, 1, errPermanent, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.shouldRetry(tt.attempt, tt.err); got != tt.want {
+				t.Errorf("shouldRetry(%d, %v) = %v, want %v", tt.attempt, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		wantErr bool
+	}{
+		{"valid", RetryPolicy{MaxAttempts: 3, InitialDelay: time.Second, Multiplier: 1.5, MaxDelay: time.Minute}, false},
+		{"negative attempts", RetryPolicy{MaxAttempts: -1}, true},
+		{"negative delay", RetryPolicy{InitialDelay: -time.Second}, true},
+		{"multiplier below one", RetryPolicy{Multiplier: 0.5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFailedRunIsRetriedUntilItSucceeds(t *testing.T) {
+	db := newMemoryDB()
+	rb := newTestInstance(t, db, Options{})
+	var calls atomic.Int32
+	task, err := rb.RegisterTaskWithOptions("flaky", func(context.Context, TaskParams, *Logger) error {
+		if calls.Add(1) < 3 {
+			return errors.New("failed")
+		}
+		return nil
+	}, versionSchema, TaskOptions{Retry: RetryPolicy{MaxAttempts: 5, InitialDelay: 10 * time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := task.ExecuteNow(TaskParams{"version": "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("flaky", "completed")) == 1 }) {
+		t.Fatal("retries did not complete the run")
+	}
+	failed := db.runsWithStatus("flaky", "failed")
+	completed := db.runsWithStatus("flaky", "completed")[0]
+	if len(failed) != 2 || completed.Attempt != 3 || completed.RetryOf != first {
+		t.Errorf("got %d failed runs and attempt %d retrying run %d, want 2 failed runs and attempt 3 retrying run %d",
+			len(failed), completed.Attempt, completed.RetryOf, first)
+	}
+}
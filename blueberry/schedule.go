@@ -0,0 +1,721 @@
+package blueberry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/robfig/cron/v3"
+)
+
+// ErrInvalidSchedule is returned when a cron expression can not be parsed
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// OverlapPolicy decides what happens when a schedule triggers while a run it started earlier is still executing
+type OverlapPolicy string
+
+const (
+	OverlapAllow   OverlapPolicy = "allow"   // Start another run next to the running one (default)
+	OverlapSkip    OverlapPolicy = "skip"    // Do not start a run, it is recorded as skipped
+	OverlapQueue   OverlapPolicy = "queue"   // Start the run once the previous runs have finished
+	OverlapReplace OverlapPolicy = "replace" // Cancel the running run and start a new one
+)
+
+func (p OverlapPolicy) validate() error {
+	switch p {
+	case "", OverlapAllow, OverlapSkip, OverlapQueue, OverlapReplace:
+		return nil
+	}
+	return fmt.Errorf("unknown overlap policy %q", p)
+}
+
+// MisfirePolicy decides what happens to the triggers of a schedule that were missed while the process was down
+type MisfirePolicy string
+
+const (
+	MisfireIgnore   MisfirePolicy = "ignore"    // Drop the missed triggers (default)
+	MisfireFireOnce MisfirePolicy = "fire_once" // Start a single catch-up run for all missed triggers
+	MisfireFireAll  MisfirePolicy = "fire_all"  // Start a catch-up run per missed trigger, up to MisfireLimit
+)
+
+func (p MisfirePolicy) validate() error {
+	switch p {
+	case "", MisfireIgnore, MisfireFireOnce, MisfireFireAll:
+		return nil
+	}
+	return fmt.Errorf("unknown misfire policy %q", p)
+}
+
+// ScheduleOptions holds the optional settings of a schedule
+type ScheduleOptions struct {
+	// Timezone is the IANA name of the zone the cron expression is evaluated in, e.g. "Europe/Berlin".
+	// When empty the default location of the BlueBerry instance is used.
+	Timezone string `json:"timezone,omitempty"`
+
+	// Overlap overrides the overlap policy of the task for this schedule
+	Overlap OverlapPolicy `json:"overlap,omitempty"`
+
+	// Misfire decides whether triggers missed while the process was down are caught up on start.
+	// Defaults to MisfireIgnore.
+	Misfire MisfirePolicy `json:"misfire,omitempty"`
+
+	// MisfireLimit caps the number of catch-up runs started by MisfireFireAll, only the most recent
+	// missed triggers are kept. It is required with MisfireFireAll.
+	MisfireLimit int `json:"misfire_limit,omitempty"`
+
+	// StartAt and EndAt limit the time the schedule triggers in, zero values leave that side open
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+
+	// MaxOccurrences expires the schedule after it started this many runs. Triggers that do not start, queue or defer
+	// a run, e.g. because they were skipped, are not counted. 0 means no limit.
+	MaxOccurrences int `json:"max_occurrences,omitempty"`
+
+	// Calendar is the name of a registered calendar excluding days and times the schedule must not run at
+	Calendar string `json:"calendar,omitempty"`
+
+	// CalendarPolicy decides whether triggers excluded by the calendar are skipped or deferred. Defaults to CalendarSkip.
+	CalendarPolicy CalendarPolicy `json:"calendar_policy,omitempty"`
+
+	// Jitter delays every trigger by a random duration up to this value
+	Jitter time.Duration `json:"jitter,omitempty" swaggertype:"integer"`
+
+	// Spread delays every trigger by a fixed offset up to this value, derived from a hash of the schedule. Schedules
+	// sharing a cron expression are spread across the window while each keeps a steady interval.
+	Spread time.Duration `json:"spread,omitempty" swaggertype:"integer"`
+
+	// Timeout overrides the timeout of the task for runs started by this schedule
+	Timeout time.Duration `json:"timeout,omitempty" swaggertype:"integer"`
+
+	// Priority overrides the priority of the task for runs started by this schedule when set, 0 included. nil
+	// keeps the priority of the task.
+	Priority *int `json:"priority,omitempty"`
+}
+
+// priority returns a copy of the priority override of the schedule, nil when the priority of the task applies
+func (o ScheduleOptions) priority() *int {
+	if o.Priority == nil {
+		return nil
+	}
+	priority := *o.Priority
+	return &priority
+}
+
+// validate checks the options that are not part of the cron expression
+func (o ScheduleOptions) validate() error {
+	if err := o.Overlap.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	if err := o.Misfire.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	if o.MisfireLimit < 0 {
+		return fmt.Errorf("%w: misfire limit must not be negative", ErrInvalidSchedule)
+	}
+	if o.Misfire == MisfireFireAll && o.MisfireLimit == 0 {
+		return fmt.Errorf("%w: misfire policy %s requires a misfire limit", ErrInvalidSchedule, MisfireFireAll)
+	}
+	if !o.StartAt.IsZero() && !o.EndAt.IsZero() && !o.EndAt.After(o.StartAt) {
+		return fmt.Errorf("%w: end time must be after the start time", ErrInvalidSchedule)
+	}
+	if o.MaxOccurrences < 0 {
+		return fmt.Errorf("%w: max occurrences must not be negative", ErrInvalidSchedule)
+	}
+	if err := o.CalendarPolicy.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	if o.Jitter < 0 || o.Spread < 0 {
+		return fmt.Errorf("%w: jitter and spread must not be negative", ErrInvalidSchedule)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("%w: timeout must not be negative", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// equal compares options by value, times that went through a store compare equal even if their location differs
+func (o ScheduleOptions) equal(other ScheduleOptions) bool {
+	if !o.StartAt.Equal(other.StartAt) || !o.EndAt.Equal(other.EndAt) {
+		return false
+	}
+	if (o.Priority == nil) != (other.Priority == nil) || (o.Priority != nil && *o.Priority != *other.Priority) {
+		return false
+	}
+	o.StartAt, o.EndAt = time.Time{}, time.Time{}
+	other.StartAt, other.EndAt = time.Time{}, time.Time{}
+	o.Priority, other.Priority = nil, nil
+	return o == other
+}
+
+// boundedSchedule limits a cron schedule to the validity window of a schedule. Once the window has passed it
+// returns the zero time, which the cron engine never triggers.
+type boundedSchedule struct {
+	cron.Schedule
+	start, end time.Time
+}
+
+func (s boundedSchedule) Next(t time.Time) time.Time {
+	if !s.start.IsZero() && t.Before(s.start) {
+		// Next returns times after t, so step back to include the start time itself
+		t = s.start.Add(-time.Nanosecond).In(t.Location())
+	}
+	next := s.Schedule.Next(t)
+	if !s.end.IsZero() && next.After(s.end) {
+		return time.Time{}
+	}
+	return next
+}
+
+// RemainingOccurrences returns how many more times the schedule triggers, or -1 when there is no limit
+func (s ScheduleInfo) RemainingOccurrences() int {
+	if s.MaxOccurrences == 0 {
+		return -1
+	}
+	if s.Occurrences >= s.MaxOccurrences {
+		return 0
+	}
+	return s.MaxOccurrences - s.Occurrences
+}
+
+// expired reports whether the schedule used up its occurrences or its validity window has passed
+func (s ScheduleInfo) expired(now time.Time) bool {
+	if s.RemainingOccurrences() == 0 {
+		return true
+	}
+	return !s.EndAt.IsZero() && now.After(s.EndAt)
+}
+
+// overlapPolicy returns the overlap policy in effect for a schedule of this task
+func (t *Task) overlapPolicy(opts ScheduleOptions) OverlapPolicy {
+	if opts.Overlap != "" {
+		return opts.Overlap
+	}
+	if t.options.Overlap != "" {
+		return t.options.Overlap
+	}
+	return OverlapAllow
+}
+
+// misfirePolicy returns the misfire policy in effect for the schedule
+func (s ScheduleInfo) misfirePolicy() MisfirePolicy {
+	if s.Misfire != "" {
+		return s.Misfire
+	}
+	return MisfireIgnore
+}
+
+// newCronParser builds the cron expression parser configured by opts
+func newCronParser(opts Options) cron.Parser {
+	fields := cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow
+	switch opts.Seconds {
+	case SecondsOptional:
+		fields |= cron.SecondOptional
+	case SecondsRequired:
+		fields |= cron.Second
+	}
+	if !opts.DisableDescriptors {
+		fields |= cron.Descriptor
+	}
+	return cron.NewParser(fields)
+}
+
+// CronFormat describes the cron expressions accepted by this instance
+func (r *BlueBerry) CronFormat() string {
+	format := "minute hour day-of-month month day-of-week"
+	switch r.options.Seconds {
+	case SecondsOptional:
+		format = "[second] " + format
+	case SecondsRequired:
+		format = "second " + format
+	}
+	if !r.options.DisableDescriptors {
+		format += ", or a descriptor such as @daily or @every 1h"
+	}
+	return format
+}
+
+// parseSchedule validates the cron expression the same way the cron engine will parse it
+func (r *BlueBerry) parseSchedule(schedule string) (cron.Schedule, error) {
+	parsed, err := r.parser.Parse(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v (format: %s)", ErrInvalidSchedule, schedule, err, r.CronFormat())
+	}
+	return parsed, nil
+}
+
+// cronSchedule parses the cron expression of a schedule and limits it to its validity window
+func (r *BlueBerry) cronSchedule(scheduleInfo ScheduleInfo) (cron.Schedule, error) {
+	parsed, err := r.parseScheduleWithOptions(scheduleInfo.Schedule, scheduleInfo.ScheduleOptions)
+	if err != nil {
+		return nil, err
+	}
+	if scheduleInfo.StartAt.IsZero() && scheduleInfo.EndAt.IsZero() {
+		return parsed, nil
+	}
+	return boundedSchedule{Schedule: parsed, start: scheduleInfo.StartAt, end: scheduleInfo.EndAt}, nil
+}
+
+// parseScheduleWithOptions validates opts and parses the cron expression in the time zone they configure
+func (r *BlueBerry) parseScheduleWithOptions(schedule string, opts ScheduleOptions) (cron.Schedule, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if _, ok := r.GetCalendar(opts.Calendar); opts.Calendar != "" && !ok {
+		return nil, fmt.Errorf("%w: %w %q", ErrInvalidSchedule, ErrCalendarNotFound, opts.Calendar)
+	}
+	if opts.Timezone == "" {
+		return r.parseSchedule(schedule)
+	}
+
+	if strings.HasPrefix(schedule, "TZ=") || strings.HasPrefix(schedule, "CRON_TZ=") {
+		return nil, fmt.Errorf("%w %q: time zone is set both in the expression and as an option", ErrInvalidSchedule, schedule)
+	}
+	if _, err := time.LoadLocation(opts.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidSchedule, opts.Timezone)
+	}
+	return r.parseSchedule("CRON_TZ=" + opts.Timezone + " " + schedule)
+}
+
+// scheduleLocation returns the zone a schedule is evaluated in
+func (r *BlueBerry) scheduleLocation(scheduleInfo ScheduleInfo) *time.Location {
+	parsed, err := r.parseScheduleWithOptions(scheduleInfo.Schedule, scheduleInfo.ScheduleOptions)
+	if err != nil {
+		return r.location
+	}
+	// Expressions without CRON_TZ are parsed as time.Local and follow the location of the cron engine
+	if spec, ok := parsed.(*cron.SpecSchedule); ok && spec.Location != time.Local {
+		return spec.Location
+	}
+	return r.location
+}
+
+// triggerDelay returns how long the trigger of a schedule due at the given time waits before it fires,
+// the sum of its spread offset and its jitter
+func (r *BlueBerry) triggerDelay(scheduleInfo ScheduleInfo, due time.Time) time.Duration {
+	var delay time.Duration
+	if scheduleInfo.Spread > 0 {
+		delay += time.Duration(hashUint64(scheduleInfo.TaskName, scheduleInfo.ID) % uint64(scheduleInfo.Spread))
+	}
+	if scheduleInfo.Jitter > 0 {
+		// Derived from a per instance seed, so the reported next execution matches the delay of the trigger
+		delay += time.Duration(hashUint64(r.jitterSeed, scheduleInfo.ID, due.Unix()) % uint64(scheduleInfo.Jitter))
+	}
+	return delay
+}
+
+// hashUint64 returns the FNV-1a hash of the given values
+func hashUint64(values ...any) uint64 {
+	h := fnv.New64a()
+	for _, value := range values {
+		_, _ = fmt.Fprintf(h, "%v/", value)
+	}
+	return h.Sum64()
+}
+
+// setNextExecution fills in the next execution of a schedule from its cron entry
+func (r *BlueBerry) setNextExecution(scheduleInfo *ScheduleInfo) {
+	next := r.cron.Entry(scheduleInfo.EntryID).Next
+	if next.IsZero() {
+		// The cron engine only computes the next run once it is started
+		parsed, err := r.cronSchedule(*scheduleInfo)
+		if err != nil {
+			return
+		}
+		next = parsed.Next(time.Now().In(r.location))
+	}
+	if next.IsZero() {
+		// The validity window has passed
+		scheduleInfo.NextExecution = 0
+		scheduleInfo.NextExecutionLocal = ""
+		return
+	}
+
+	next = next.Add(r.triggerDelay(*scheduleInfo, next))
+	scheduleInfo.NextExecution = next.UTC().Unix()
+	scheduleInfo.NextExecutionLocal = next.In(r.scheduleLocation(*scheduleInfo)).Format(time.RFC3339)
+}
+
+// UpdateSchedule replaces the parameters and the cron expression of an existing schedule while keeping its schedule ID
+// and its options
+func (t *Task) UpdateSchedule(scheduleID int, params TaskParams, schedule string) (ScheduleInfo, error) {
+	current, err := t.GetSchedule(scheduleID)
+	if err != nil {
+		return ScheduleInfo{}, err
+	}
+	return t.UpdateScheduleWithOptions(scheduleID, params, schedule, current.ScheduleOptions)
+}
+
+// UpdateScheduleWithOptions replaces the parameters, the cron expression and the options of an existing schedule
+// while keeping its schedule ID
+func (t *Task) UpdateScheduleWithOptions(scheduleID int, params TaskParams, schedule string, opts ScheduleOptions) (ScheduleInfo, error) {
+	if err := t.ValidateParams(params); err != nil {
+		return ScheduleInfo{}, err
+	}
+	if _, err := t.blueBerry.parseScheduleWithOptions(schedule, opts); err != nil {
+		return ScheduleInfo{}, err
+	}
+	if err := t.blueBerry.validateParamTemplates(ScheduleInfo{ID: scheduleID, TaskName: t.name, Schedule: schedule, Params: params, ScheduleOptions: opts}); err != nil {
+		return ScheduleInfo{}, err
+	}
+
+	t.blueBerry.schedulesMux.Lock()
+	defer t.blueBerry.schedulesMux.Unlock()
+
+	loadedSchedules, ok := t.blueBerry.schedules.Load(t.name)
+	if !ok {
+		return ScheduleInfo{}, fmt.Errorf("%w: %d", ErrScheduleNotFound, scheduleID)
+	}
+	schedules := append([]ScheduleInfo(nil), loadedSchedules.([]ScheduleInfo)...)
+
+	for i := range schedules {
+		if schedules[i].ID != scheduleID {
+			continue
+		}
+
+		updated := schedules[i]
+		updated.Schedule = schedule
+		updated.Params = params
+		updated.ScheduleOptions = opts
+
+		if err := t.blueBerry.db.SaveSchedule(context.Background(), &updated); err != nil {
+			return ScheduleInfo{}, fmt.Errorf("unable to save schedule: %w", err)
+		}
+
+		t.blueBerry.cron.Remove(schedules[i].EntryID)
+		if !updated.Paused {
+			if err := t.addToCron(&updated); err != nil {
+				return ScheduleInfo{}, err
+			}
+		}
+
+		schedules[i] = updated
+		t.blueBerry.schedules.Store(t.name, schedules)
+		return updated, nil
+	}
+
+	return ScheduleInfo{}, fmt.Errorf("%w: %d", ErrScheduleNotFound, scheduleID)
+}
+
+// PauseSchedule stops a schedule from triggering while keeping its configuration
+func (t *Task) PauseSchedule(scheduleID int) (ScheduleInfo, error) {
+	return t.setSchedulePaused(scheduleID, true)
+}
+
+// ResumeSchedule hands a paused schedule back to the cron engine
+func (t *Task) ResumeSchedule(scheduleID int) (ScheduleInfo, error) {
+	return t.setSchedulePaused(scheduleID, false)
+}
+
+func (t *Task) setSchedulePaused(scheduleID int, paused bool) (ScheduleInfo, error) {
+	t.blueBerry.schedulesMux.Lock()
+	defer t.blueBerry.schedulesMux.Unlock()
+
+	loadedSchedules, ok := t.blueBerry.schedules.Load(t.name)
+	if !ok {
+		return ScheduleInfo{}, fmt.Errorf("%w: %d", ErrScheduleNotFound, scheduleID)
+	}
+	schedules := append([]ScheduleInfo(nil), loadedSchedules.([]ScheduleInfo)...)
+
+	for i := range schedules {
+		if schedules[i].ID != scheduleID {
+			continue
+		}
+		if schedules[i].Paused == paused {
+			return schedules[i], nil
+		}
+
+		updated := schedules[i]
+		updated.Paused = paused
+		if err := t.blueBerry.db.SaveSchedule(context.Background(), &updated); err != nil {
+			return ScheduleInfo{}, fmt.Errorf("unable to save schedule: %w", err)
+		}
+
+		if paused {
+			t.blueBerry.cron.Remove(updated.EntryID)
+			updated.EntryID = 0
+			updated.NextExecution = 0
+			updated.NextExecutionLocal = ""
+		} else if err := t.addToCron(&updated); err != nil {
+			return ScheduleInfo{}, err
+		}
+
+		schedules[i] = updated
+		t.blueBerry.schedules.Store(t.name, schedules)
+		return updated, nil
+	}
+
+	return ScheduleInfo{}, fmt.Errorf("%w: %d", ErrScheduleNotFound, scheduleID)
+}
+
+// fireSchedule is invoked by the cron engine whenever a schedule triggers, and on start for catch-up runs of
+// triggers missed while the process was down. firedAt is the time the trigger was due. It reports whether the
+// trigger started, queued or deferred a run, which counts as an occurrence of the schedule.
+func (t *Task) fireSchedule(scheduleInfo ScheduleInfo, firedAt time.Time, catchUp bool) bool {
+	opts := runOptions{scheduleID: scheduleInfo.ID, catchUp: catchUp, timeout: scheduleInfo.Timeout, priority: scheduleInfo.priority()}
+	params, err := t.blueBerry.renderParams(scheduleInfo, firedAt)
+	if err != nil {
+		t.recordFailedRun(TaskParams(scheduleInfo.Params), opts, fmt.Sprintf("Trigger of schedule %d failed: %v", scheduleInfo.ID, err))
+		return false
+	}
+	if t.blueBerry.IsMaintenanceMode() {
+		t.recordSkippedRun(params, opts, fmt.Sprintf("Skipped trigger of schedule %d: maintenance mode is enabled", scheduleInfo.ID))
+		return false
+	}
+	if start, deferred := t.applyCalendar(scheduleInfo, params, firedAt, opts); !start {
+		// Deferred triggers of a schedule with the queue overlap policy count once their run starts
+		return deferred && t.overlapPolicy(scheduleInfo.ScheduleOptions) != OverlapQueue
+	}
+
+	return t.admitTrigger(scheduleInfo, func(reason string) {
+		t.recordSkippedRun(params, opts, reason)
+	}, func(overlapQueue bool) *execution {
+		opts.overlapQueue = overlapQueue
+		return t.startScheduledRun(params, opts)
+	})
+}
+
+// admitTrigger applies the overlap policy of the schedule to a trigger that is due. skip records a trigger that
+// must not start a run, start starts or queues the run and returns nil if the unique policy or an error kept it from
+// starting. It reports whether the trigger started or queued a run that counts as an occurrence of the schedule now,
+// runs of the queue overlap policy wait in the run queue for the other runs of the schedule and count once they start.
+func (t *Task) admitTrigger(scheduleInfo ScheduleInfo, skip func(reason string), start func(overlapQueue bool) *execution) bool {
+	running := t.blueBerry.runningForSchedule(scheduleInfo.ID)
+	switch t.overlapPolicy(scheduleInfo.ScheduleOptions) {
+	case OverlapSkip:
+		if len(running) > 0 {
+			skip(fmt.Sprintf("Skipped trigger of schedule %d: run %d is still running", scheduleInfo.ID, running[0].runID))
+			return false
+		}
+	case OverlapReplace:
+		for _, exec := range running {
+			log.Infof("replacing run %d of schedule %d", exec.runID, scheduleInfo.ID)
+			if err := t.blueBerry.CancelExecutionByID(exec.runID); err != nil {
+				log.Warnf("unable to cancel run %d of schedule %d: %v", exec.runID, scheduleInfo.ID, err)
+			}
+		}
+	case OverlapQueue:
+		start(true)
+		return false
+	}
+
+	return start(false) != nil
+}
+
+// queuesTriggers reports whether the runs of the schedule wait for each other under the queue overlap policy
+func (t *Task) queuesTriggers(scheduleID int) bool {
+	if scheduleID == 0 {
+		return false
+	}
+	scheduleInfo, err := t.GetSchedule(scheduleID)
+	return err == nil && t.overlapPolicy(scheduleInfo.ScheduleOptions) == OverlapQueue
+}
+
+func (t *Task) startScheduledRun(params TaskParams, opts runOptions) *execution {
+	exec, err := t.execute(params, opts)
+	var alreadyRunning *AlreadyRunningError
+	if errors.As(err, &alreadyRunning) {
+		t.recordSkippedRun(params, opts, fmt.Sprintf("Skipped trigger of schedule %d: run %d with the same parameters is still running", opts.scheduleID, alreadyRunning.RunID))
+		return nil
+	}
+	if err != nil {
+		log.Errorf("unable to execute schedule %d of task %s: %v", opts.scheduleID, t.name, err)
+		return nil
+	}
+	return exec
+}
+
+// scheduleActive reports whether the schedule is still registered and has occurrences left, triggers of other
+// schedules must not start a run
+func (r *BlueBerry) scheduleActive(taskName string, scheduleID int) bool {
+	r.schedulesMux.RLock()
+	defer r.schedulesMux.RUnlock()
+
+	loadedSchedules, ok := r.schedules.Load(taskName)
+	if !ok {
+		return false
+	}
+	for _, schedule := range loadedSchedules.([]ScheduleInfo) {
+		if schedule.ID == scheduleID {
+			return schedule.RemainingOccurrences() != 0
+		}
+	}
+	return false
+}
+
+// recordScheduleFired persists the time a schedule last triggered and adds the runs the trigger started to its
+// occurrences. The last trigger is the starting point for catching up on missed triggers after a restart, so it
+// moves for skipped triggers as well, a zero firedAt leaves it as it is. Schedules that used up their occurrences are
+// removed from the cron engine. It reports false when the schedule was deleted or had already expired.
+func (r *BlueBerry) recordScheduleFired(taskName string, scheduleID int, firedAt time.Time, occurrences int) bool {
+	r.schedulesMux.Lock()
+	defer r.schedulesMux.Unlock()
+
+	loadedSchedules, ok := r.schedules.Load(taskName)
+	if !ok {
+		return false
+	}
+	schedules := append([]ScheduleInfo(nil), loadedSchedules.([]ScheduleInfo)...)
+
+	for i := range schedules {
+		if schedules[i].ID != scheduleID {
+			continue
+		}
+		if schedules[i].RemainingOccurrences() == 0 {
+			return false
+		}
+
+		// Deleted schedules are not found above, so they are never written back to the store
+		if !firedAt.IsZero() {
+			schedules[i].LastFired = firedAt.UTC().Unix()
+		}
+		schedules[i].Occurrences += occurrences
+		if schedules[i].RemainingOccurrences() == 0 {
+			log.Infof("schedule %d of task %s expired after %d occurrences", scheduleID, taskName, schedules[i].Occurrences)
+			r.cron.Remove(schedules[i].EntryID)
+			schedules[i].EntryID = 0
+		}
+		if err := r.db.SaveSchedule(context.Background(), &schedules[i]); err != nil {
+			log.Warnf("unable to save last trigger of schedule %d: %v", scheduleID, err)
+		}
+		r.schedules.Store(taskName, schedules)
+		return true
+	}
+	return false
+}
+
+// missedTriggers returns the most recent keep trigger times of a schedule after its last trigger and before now,
+// oldest first, and whether older missed triggers were dropped.
+func (r *BlueBerry) missedTriggers(scheduleInfo ScheduleInfo, now time.Time, keep int) ([]time.Time, bool, error) {
+	parsed, err := r.cronSchedule(scheduleInfo)
+	if err != nil {
+		return nil, false, err
+	}
+	lastFired := time.Unix(scheduleInfo.LastFired, 0).In(r.location)
+
+	// Search back from now in growing windows, so that a long downtime does not walk every trigger since
+	for window := time.Minute; ; window *= 2 {
+		from := now.Add(-window)
+		reachedLast := window >= now.Sub(lastFired)
+		if reachedLast {
+			from = lastFired
+		}
+
+		missed := make([]time.Time, 0, keep)
+		found := 0
+		for next := parsed.Next(from); !next.IsZero() && next.Before(now); next = parsed.Next(next) {
+			if len(missed) == keep {
+				missed = append(missed[:0], missed[1:]...)
+			}
+			missed = append(missed, next)
+			found++
+		}
+		if found >= keep || reachedLast {
+			dropped := len(missed) > 0 && parsed.Next(lastFired).Before(missed[0])
+			return missed, dropped, nil
+		}
+	}
+}
+
+// catchUpMisfires starts catch-up runs for the triggers missed while the process was down, as configured by the
+// misfire policy of each schedule
+func (r *BlueBerry) catchUpMisfires() {
+	now := time.Now()
+	r.tasks.Range(func(_, value interface{}) bool {
+		task := value.(*Task)
+		for _, scheduleInfo := range r.getSchedules(task.name) {
+			task.catchUpSchedule(scheduleInfo, now)
+		}
+		return true
+	})
+}
+
+func (t *Task) catchUpSchedule(scheduleInfo ScheduleInfo, now time.Time) {
+	// Schedules that never triggered have nothing to catch up on, paused and expired ones are not supposed to run
+	if scheduleInfo.Paused || scheduleInfo.LastFired == 0 || scheduleInfo.expired(now) {
+		return
+	}
+
+	keep := 1
+	switch scheduleInfo.misfirePolicy() {
+	case MisfireIgnore:
+		return
+	case MisfireFireAll:
+		// Schedules stored before the limit was required fall back to a single run
+		keep = max(scheduleInfo.MisfireLimit, 1)
+	}
+	if remaining := scheduleInfo.RemainingOccurrences(); remaining > 0 {
+		keep = min(keep, remaining)
+	}
+
+	missed, dropped, err := t.blueBerry.missedTriggers(scheduleInfo, now, keep)
+	if err != nil {
+		log.Warnf("unable to catch up on schedule %d: %v", scheduleInfo.ID, err)
+		return
+	}
+	if len(missed) == 0 {
+		return
+	}
+
+	if dropped {
+		log.Infof("schedule %d of task %s missed more than %d triggers, starting %d catch-up runs for the most recent ones", scheduleInfo.ID, t.name, len(missed), len(missed))
+	} else {
+		log.Infof("schedule %d of task %s missed %d triggers, starting %d catch-up runs", scheduleInfo.ID, t.name, len(missed), len(missed))
+	}
+	// Record the catch-up before starting the runs so that another restart does not repeat them
+	lastMissed := missed[len(missed)-1]
+	if !t.blueBerry.recordScheduleFired(t.name, scheduleInfo.ID, lastMissed, 0) {
+		return
+	}
+	started := 0
+	for _, firedAt := range missed {
+		if t.fireSchedule(scheduleInfo, firedAt, true) {
+			started++
+		}
+	}
+	if started > 0 {
+		t.blueBerry.recordScheduleFired(t.name, scheduleInfo.ID, lastMissed, started)
+	}
+}
+
+// recordSkippedRun stores a run with the "skipped" status so that suppressed triggers stay visible
+func (t *Task) recordSkippedRun(params TaskParams, opts runOptions, reason string) {
+	if logger := t.recordEndedRun(params, opts, "skipped"); logger != nil {
+		_ = logger.Info(reason)
+	}
+}
+
+// recordFailedRun stores a run for a trigger that could not start, with the error as its only log entry
+func (t *Task) recordFailedRun(params TaskParams, opts runOptions, reason string) {
+	if logger := t.recordEndedRun(params, opts, "failed"); logger != nil {
+		_ = logger.Error(reason)
+	}
+}
+
+// recordEndedRun stores a run that ends as soon as it is created and returns a logger for it, nil if it could not
+// be stored
+func (t *Task) recordEndedRun(params TaskParams, opts runOptions, status string) *Logger {
+	now := time.Now().UTC()
+	taskRun := &TaskRun{
+		TaskName:  t.name,
+		StartTime: now,
+		EndTime:   now,
+		Params:    params,
+		Status:    status,
+		CatchUp:   opts.catchUp,
+		Attempt:   1,
+		Priority:  t.priority(opts),
+
+		ScheduleID: opts.scheduleID,
+		Timeout:    t.timeout(opts),
+	}
+
+	if err := t.blueBerry.db.SaveTaskRun(context.Background(), taskRun); err != nil {
+		log.Errorf("unable to record %s run of task %s: %v", status, t.name, err)
+		return nil
+	}
+	return &Logger{taskRun: taskRun, db: t.blueBerry.db}
+}
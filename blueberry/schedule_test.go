@@ -0,0 +1,268 @@
+package blueberry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCatchUpCountsStartedRuns(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxOccurrences  int
+		maintenance     bool
+		wantOccurrences int
+		wantSkipped     int
+		wantExpired     bool
+	}{
+		{"every missed trigger starts a run", 0, false, 4, 0, false},
+		{"runs are capped by the remaining occurrences", 2, false, 2, 0, true},
+		{"skipped triggers are not counted", 2, true, 0, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			rb := newTestInstance(t, db, Options{})
+			rb.SetMaintenanceMode(tt.maintenance)
+			task, _ := rb.RegisterTask("report", (&runCounter{}).task, versionSchema)
+			scheduleInfo, err := task.CreateScheduleWithOptions(TaskParams{"version": "v1"}, "* * * * * *",
+				ScheduleOptions{Misfire: MisfireFireAll, MisfireLimit: 10, MaxOccurrences: tt.maxOccurrences})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Four triggers were missed between the last one and now
+			now := time.Now().Truncate(time.Second)
+			scheduleInfo.LastFired = now.Add(-5 * time.Second).Unix()
+			task.catchUpSchedule(scheduleInfo, now)
+
+			current, err := task.GetSchedule(scheduleInfo.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current.Occurrences != tt.wantOccurrences || current.Expired != tt.wantExpired {
+				t.Errorf("occurrences = %d (expired %v), want %d (expired %v)", current.Occurrences, current.Expired, tt.wantOccurrences, tt.wantExpired)
+			}
+			if current.LastFired != now.Add(-time.Second).Unix() {
+				t.Errorf("last trigger = %d, want %d", current.LastFired, now.Add(-time.Second).Unix())
+			}
+			if skipped := db.runsWithStatus("report", "skipped"); len(skipped) != tt.wantSkipped {
+				t.Errorf("%d skipped runs, want %d", len(skipped), tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestCatchUpMisfirePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     ScheduleOptions
+		downtime time.Duration // Time since the last trigger of the every second schedule
+		want     []string      // Trigger times of the catch-up runs, relative to now
+	}{
+		{"ignore", ScheduleOptions{Misfire: MisfireIgnore}, 5 * time.Second, nil},
+		{"fire once", ScheduleOptions{Misfire: MisfireFireOnce}, 5 * time.Second, []string{"-1s"}},
+		{"fire all", ScheduleOptions{Misfire: MisfireFireAll, MisfireLimit: 10}, 5 * time.Second, []string{"-4s", "-3s", "-2s", "-1s"}},
+		{"fire all up to the limit", ScheduleOptions{Misfire: MisfireFireAll, MisfireLimit: 2}, 5 * time.Second, []string{"-2s", "-1s"}},
+		{"a day of downtime", ScheduleOptions{Misfire: MisfireFireAll, MisfireLimit: 3}, 24 * time.Hour, []string{"-3s", "-2s", "-1s"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			rb := newTestInstance(t, db, Options{Location: time.UTC})
+			counter := &runCounter{}
+			task, _ := rb.RegisterTask("report", counter.task, versionSchema)
+			scheduleInfo, err := task.CreateScheduleWithOptions(TaskParams{"version": `{{ unix .ScheduledTime }}`}, "* * * * * *", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Now().Truncate(time.Second)
+			scheduleInfo.LastFired = now.Add(-tt.downtime).Unix()
+			started := time.Now()
+			task.catchUpSchedule(scheduleInfo, now)
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Errorf("catching up took %s", elapsed)
+			}
+
+			if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("report", "completed")) == len(tt.want) }) {
+				t.Fatalf("%d catch-up runs completed, want %d", len(db.runsWithStatus("report", "completed")), len(tt.want))
+			}
+			for _, offset := range tt.want {
+				d, _ := time.ParseDuration(offset)
+				if version := strconv.FormatInt(now.Add(d).Unix(), 10); counter.count(version) != 1 {
+					t.Errorf("trigger at %s did not start a single catch-up run", offset)
+				}
+			}
+			for _, taskRun := range db.runsWithStatus("report", "completed") {
+				if !taskRun.CatchUp {
+					t.Errorf("run %d is not flagged as catch-up run", taskRun.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestOverlapPolicies(t *testing.T) {
+	tests := []struct {
+		name          string
+		overlap       OverlapPolicy
+		wantSecond    bool           // Whether the second trigger counts as an occurrence right away
+		wantRunning   map[string]int // Runs by status while the first run is still running
+		wantCompleted int
+	}{
+		{"allow", OverlapAllow, true, map[string]int{"started": 2}, 2},
+		{"skip", OverlapSkip, false, map[string]int{"started": 1, "skipped": 1}, 1},
+		{"replace", OverlapReplace, true, map[string]int{"started": 1, "cancelled": 1}, 1},
+		{"queue", OverlapQueue, false, map[string]int{"started": 1, "queued": 1}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			rb := newTestInstance(t, db, Options{})
+			release := make(chan struct{})
+			task, _ := rb.RegisterTask("report", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+				return nil
+			}, versionSchema)
+			scheduleInfo, err := task.CreateScheduleWithOptions(TaskParams{"version": "v1"}, "0 0 1 1 *", ScheduleOptions{Overlap: tt.overlap})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task.fireSchedule(scheduleInfo, time.Now(), false)
+			if fired := task.fireSchedule(scheduleInfo, time.Now(), false); fired != tt.wantSecond {
+				t.Errorf("second trigger counted = %v, want %v", fired, tt.wantSecond)
+			}
+			for status, want := range tt.wantRunning {
+				if !waitFor(t, time.Second, func() bool { return len(db.runsWithStatus("report", status)) == want }) {
+					t.Errorf("%d %s runs, want %d", len(db.runsWithStatus("report", status)), status, want)
+				}
+			}
+			if queue := rb.GetQueue(); len(queue) != tt.wantRunning["queued"] {
+				t.Errorf("queue = %+v, want %d runs", queue, tt.wantRunning["queued"])
+			} else if len(queue) > 0 && queue[0].ScheduleID != scheduleInfo.ID {
+				t.Errorf("queued run belongs to schedule %d, want %d", queue[0].ScheduleID, scheduleInfo.ID)
+			}
+
+			close(release)
+			if !waitFor(t, 3*time.Second, func() bool { return len(db.runsWithStatus("report", "completed")) == tt.wantCompleted }) {
+				t.Fatalf("%d completed runs, want %d", len(db.runsWithStatus("report", "completed")), tt.wantCompleted)
+			}
+			if tt.overlap == OverlapQueue {
+				// The queued trigger counts as an occurrence once its run starts
+				current, _ := task.GetSchedule(scheduleInfo.ID)
+				if current.Occurrences != 2 {
+					t.Errorf("occurrences = %d, want 2", current.Occurrences)
+				}
+			}
+		})
+	}
+}
+
+func TestOverlapQueueCountsOccurrencesOnStart(t *testing.T) {
+	db := newMemoryDB()
+	rb := newTestInstance(t, db, Options{})
+	release := make(chan struct{})
+	task, _ := rb.RegisterTask("report", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}, versionSchema)
+	scheduleInfo, err := task.CreateScheduleWithOptions(TaskParams{"version": "v1"}, "0 0 1 1 *",
+		ScheduleOptions{Overlap: OverlapQueue, MaxOccurrences: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three triggers queue up behind the first run, only one of them fits into the occurrences left
+	for i := 0; i < 3; i++ {
+		task.fireSchedule(scheduleInfo, time.Now(), false)
+	}
+	if current, _ := task.GetSchedule(scheduleInfo.ID); current.Occurrences != 1 {
+		t.Errorf("occurrences = %d while two triggers are queued, want 1", current.Occurrences)
+	}
+
+	close(release)
+	if !waitFor(t, 3*time.Second, func() bool {
+		return len(db.runsWithStatus("report", "completed")) == 2 && len(db.runsWithStatus("report", "skipped")) == 1
+	}) {
+		t.Fatalf("%d completed and %d skipped runs, want 2 and 1", len(db.runsWithStatus("report", "completed")), len(db.runsWithStatus("report", "skipped")))
+	}
+	if current, _ := task.GetSchedule(scheduleInfo.ID); current.Occurrences != 2 || !current.Expired {
+		t.Errorf("occurrences = %d (expired %v), want 2 (expired true)", current.Occurrences, current.Expired)
+	}
+}
+
+func TestTriggerDelay(t *testing.T) {
+	rb := newTestInstance(t, newMemoryDB(), Options{})
+	due := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule ScheduleInfo
+		max      time.Duration
+	}{
+		{"no delay", ScheduleInfo{ID: 1, TaskName: "report"}, 0},
+		{"spread", ScheduleInfo{ID: 1, TaskName: "report", ScheduleOptions: ScheduleOptions{Spread: time.Hour}}, time.Hour},
+		{"jitter", ScheduleInfo{ID: 1, TaskName: "report", ScheduleOptions: ScheduleOptions{Jitter: time.Minute}}, time.Minute},
+		{"jitter and spread", ScheduleInfo{ID: 1, TaskName: "report", ScheduleOptions: ScheduleOptions{Jitter: time.Minute, Spread: time.Hour}}, time.Hour + time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay := rb.triggerDelay(tt.schedule, due)
+			if delay < 0 || delay > tt.max || (tt.max > 0 && delay == 0) {
+				t.Errorf("delay = %s, want up to %s", delay, tt.max)
+			}
+			// The reported next execution relies on the same delay for the same trigger
+			if again := rb.triggerDelay(tt.schedule, due); again != delay {
+				t.Errorf("delay changed from %s to %s", delay, again)
+			}
+		})
+	}
+
+	// Spread keeps a steady offset per schedule, while schedules sharing an expression are spread apart
+	spread := ScheduleOptions{Spread: time.Hour}
+	first := ScheduleInfo{ID: 1, TaskName: "report", ScheduleOptions: spread}
+	if rb.triggerDelay(first, due) != rb.triggerDelay(first, due.Add(time.Hour)) {
+		t.Error("spread differs between triggers of the same schedule")
+	}
+	other := ScheduleInfo{ID: 2, TaskName: "report", ScheduleOptions: spread}
+	if rb.triggerDelay(first, due) == rb.triggerDelay(other, due) {
+		t.Error("two schedules got the same spread")
+	}
+}
+
+func TestScheduleOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ScheduleOptions
+		wantErr bool
+	}{
+		{"defaults", ScheduleOptions{}, false},
+		{"fire all with a limit", ScheduleOptions{Misfire: MisfireFireAll, MisfireLimit: 7}, false},
+		{"fire all without a limit", ScheduleOptions{Misfire: MisfireFireAll}, true},
+		{"negative misfire limit", ScheduleOptions{Misfire: MisfireFireAll, MisfireLimit: -1}, true},
+		{"unknown overlap policy", ScheduleOptions{Overlap: "sometimes"}, true},
+		{"end before start", ScheduleOptions{StartAt: time.Now(), EndAt: time.Now().Add(-time.Hour)}, true},
+		{"negative max occurrences", ScheduleOptions{MaxOccurrences: -1}, true},
+		{"negative jitter", ScheduleOptions{Jitter: -time.Second}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, want an error: %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("validate() = %v, want ErrInvalidSchedule", err)
+			}
+		})
+	}
+}
@@ -8,12 +8,18 @@ import (
 	"github.com/ersauravadhikari/blueberry-go/blueberry"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 )
 
 type Metadata struct {
-	LastTaskID    int              `json:"last_task_id"`
-	TaskNameToIDs map[string][]int `json:"task_name_to_ids"`
+	LastTaskID     int              `json:"last_task_id"`
+	LastScheduleID int              `json:"last_schedule_id"`
+	TaskNameToIDs  map[string][]int `json:"task_name_to_ids"`
+	RunStatuses    map[int]string   `json:"run_statuses"` // Run ID to the status of the run, to find runs by status without reading them all
+
+	// Task name to the idempotency keys of its runs and the ID of the most recent run stored with each key
+	IdempotencyKeys map[string]map[string]int `json:"idempotency_keys"`
 }
 
 type FileStoreDB struct {
@@ -30,7 +36,9 @@ func NewFileStoreDB(baseDir string) (*FileStoreDB, error) {
 	db := &FileStoreDB{
 		baseDir: baseDir,
 		metadata: Metadata{
-			TaskNameToIDs: make(map[string][]int),
+			TaskNameToIDs:   make(map[string][]int),
+			RunStatuses:     make(map[int]string),
+			IdempotencyKeys: make(map[string]map[string]int),
 		},
 	}
 
@@ -57,13 +65,67 @@ func (db *FileStoreDB) loadMetadata() error {
 	defer f.Close()
 
 	decoder := json.NewDecoder(f)
-	return decoder.Decode(&db.metadata)
+	if err := decoder.Decode(&db.metadata); err != nil {
+		return err
+	}
+	if db.metadata.RunStatuses != nil && db.metadata.IdempotencyKeys != nil {
+		return nil
+	}
+
+	// Metadata written before run statuses or idempotency keys were tracked, read them once from the runs
+	db.metadata.RunStatuses = make(map[int]string)
+	db.metadata.IdempotencyKeys = make(map[string]map[string]int)
+	for taskName, ids := range db.metadata.TaskNameToIDs {
+		// Run IDs of a task are appended in increasing order, so the most recent run of a key is indexed last
+		for _, id := range ids {
+			taskRun, err := db.readTaskRun(taskName, id)
+			if err != nil {
+				return err
+			}
+			db.metadata.RunStatuses[id] = taskRun.Status
+			db.indexIdempotencyKey(&taskRun)
+		}
+	}
+	return db.saveMetadata()
 }
 
-func (db *FileStoreDB) saveMetadata() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
+// indexIdempotencyKey records the run as the most recent run of its idempotency key and reports whether the index
+// changed, the caller must hold db.mu
+func (db *FileStoreDB) indexIdempotencyKey(taskRun *blueberry.TaskRun) bool {
+	if taskRun.IdempotencyKey == "" {
+		return false
+	}
+	return db.setIdempotencyKey(taskRun.TaskName, taskRun.IdempotencyKey, taskRun.ID)
+}
+
+// setIdempotencyKey points the idempotency key of the task to the run unless it points to a more recent one and
+// reports whether the index changed, the caller must hold db.mu
+func (db *FileStoreDB) setIdempotencyKey(taskName, key string, id int) bool {
+	keys := db.metadata.IdempotencyKeys[taskName]
+	if keys == nil {
+		keys = make(map[string]int)
+		db.metadata.IdempotencyKeys[taskName] = keys
+	}
+	if keys[key] >= id {
+		return false
+	}
+	keys[key] = id
+	return true
+}
 
+// readTaskRun reads a stored run, the caller must hold db.mu
+func (db *FileStoreDB) readTaskRun(taskName string, id int) (blueberry.TaskRun, error) {
+	var taskRun blueberry.TaskRun
+	data, err := os.ReadFile(filepath.Join(db.baseDir, taskName, fmt.Sprintf("task_%d.json", id)))
+	if err != nil {
+		return taskRun, err
+	}
+	err = json.Unmarshal(data, &taskRun)
+	return taskRun, err
+}
+
+// saveMetadata writes the metadata file, the caller must hold db.mu
+func (db *FileStoreDB) saveMetadata() error {
 	metadataFilePath := filepath.Join(db.baseDir, "metadata.json")
 	f, err := os.Create(metadataFilePath)
 	if err != nil {
@@ -79,7 +141,8 @@ func (db *FileStoreDB) SaveTaskRun(ctx context.Context, taskRun *blueberry.TaskR
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	if taskRun.ID == 0 {
+	isNew := taskRun.ID == 0
+	if isNew {
 		db.metadata.LastTaskID++
 		taskRun.ID = db.metadata.LastTaskID
 	}
@@ -101,7 +164,16 @@ func (db *FileStoreDB) SaveTaskRun(ctx context.Context, taskRun *blueberry.TaskR
 		return err
 	}
 
-	db.metadata.TaskNameToIDs[taskRun.TaskName] = append(db.metadata.TaskNameToIDs[taskRun.TaskName], taskRun.ID)
+	statusChanged := db.metadata.RunStatuses[taskRun.ID] != taskRun.Status
+	db.metadata.RunStatuses[taskRun.ID] = taskRun.Status
+	keyIndexed := db.indexIdempotencyKey(taskRun)
+	if !isNew && !statusChanged && !keyIndexed {
+		return nil
+	}
+
+	if isNew {
+		db.metadata.TaskNameToIDs[taskRun.TaskName] = append(db.metadata.TaskNameToIDs[taskRun.TaskName], taskRun.ID)
+	}
 	return db.saveMetadata()
 }
 
@@ -157,6 +229,31 @@ func (db *FileStoreDB) GetTaskRuns(ctx context.Context) ([]blueberry.TaskRun, er
 	return taskRuns, nil
 }
 
+// GetTaskRunsByStatus returns the runs with the given status, oldest first
+func (db *FileStoreDB) GetTaskRunsByStatus(ctx context.Context, status string) ([]blueberry.TaskRun, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	taskRuns := []blueberry.TaskRun{}
+	for taskName, ids := range db.metadata.TaskNameToIDs {
+		for _, id := range ids {
+			if db.metadata.RunStatuses[id] != status {
+				continue
+			}
+			taskRun, err := db.readTaskRun(taskName, id)
+			if err != nil {
+				return nil, err
+			}
+			taskRuns = append(taskRuns, taskRun)
+		}
+	}
+
+	sort.Slice(taskRuns, func(i, j int) bool {
+		return taskRuns[i].ID < taskRuns[j].ID
+	})
+	return taskRuns, nil
+}
+
 func (db *FileStoreDB) GetTaskRunByID(ctx context.Context, id int) (*blueberry.TaskRun, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
@@ -185,6 +282,33 @@ func (db *FileStoreDB) GetTaskRunByID(ctx context.Context, id int) (*blueberry.T
 	return nil, fmt.Errorf("task run with ID %d not found", id)
 }
 
+// GetTaskRunByIdempotencyKey returns the most recent run of the task stored or saved with the idempotency key, nil if there is none
+func (db *FileStoreDB) GetTaskRunByIdempotencyKey(ctx context.Context, taskName, key string) (*blueberry.TaskRun, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	id, ok := db.metadata.IdempotencyKeys[taskName][key]
+	if !ok {
+		return nil, nil
+	}
+	taskRun, err := db.readTaskRun(taskName, id)
+	if err != nil {
+		return nil, err
+	}
+	return &taskRun, nil
+}
+
+// SaveIdempotencyKey makes the idempotency key of the task return a run that was started without it
+func (db *FileStoreDB) SaveIdempotencyKey(ctx context.Context, taskName, key string, taskRunID int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !db.setIdempotencyKey(taskName, key, taskRunID) {
+		return nil
+	}
+	return db.saveMetadata()
+}
+
 func (db *FileStoreDB) GetTaskRunLogs(ctx context.Context, taskRunID int) ([]blueberry.TaskRunLog, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
@@ -209,10 +333,10 @@ func (db *FileStoreDB) GetTaskRunLogs(ctx context.Context, taskRunID int) ([]blu
 	return taskRunLogs, scanner.Err()
 }
 
-func (db *FileStoreDB) GetPaginatedTaskRunLogs(ctx context.Context, taskRunID int, level string, page, size int) ([]blueberry.TaskRunLog, error) {
+func (db *FileStoreDB) GetPaginatedTaskRunLogs(ctx context.Context, taskRunID int, level string, page, size int) ([]blueberry.TaskRunLog, int, error) {
 	allLogs, err := db.GetTaskRunLogs(ctx, taskRunID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	var filteredLogs []blueberry.TaskRunLog
@@ -225,13 +349,13 @@ func (db *FileStoreDB) GetPaginatedTaskRunLogs(ctx context.Context, taskRunID in
 	start := (page - 1) * size
 	end := start + size
 	if start > len(filteredLogs) {
-		return []blueberry.TaskRunLog{}, nil
+		return []blueberry.TaskRunLog{}, len(filteredLogs), nil
 	}
 	if end > len(filteredLogs) {
 		end = len(filteredLogs)
 	}
 
-	return filteredLogs[start:end], nil
+	return filteredLogs[start:end], len(filteredLogs), nil
 }
 
 func (db *FileStoreDB) GetPaginatedTaskRunsForTaskName(ctx context.Context, name string, page, limit int) ([]blueberry.TaskRun, error) {
@@ -284,6 +408,95 @@ func (db *FileStoreDB) GetTaskRunsCountForTaskName(ctx context.Context, name str
 	return len(ids), nil
 }
 
+func (db *FileStoreDB) loadSchedules() ([]blueberry.ScheduleInfo, error) {
+	schedulesFilePath := filepath.Join(db.baseDir, "schedules.json")
+	if _, err := os.Stat(schedulesFilePath); os.IsNotExist(err) {
+		return []blueberry.ScheduleInfo{}, nil
+	}
+
+	f, err := os.Open(schedulesFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var schedules []blueberry.ScheduleInfo
+	decoder := json.NewDecoder(f)
+	if err := decoder.Decode(&schedules); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (db *FileStoreDB) writeSchedules(schedules []blueberry.ScheduleInfo) error {
+	schedulesFilePath := filepath.Join(db.baseDir, "schedules.json")
+	f, err := os.Create(schedulesFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	return encoder.Encode(schedules)
+}
+
+func (db *FileStoreDB) SaveSchedule(ctx context.Context, schedule *blueberry.ScheduleInfo) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	schedules, err := db.loadSchedules()
+	if err != nil {
+		return err
+	}
+
+	if schedule.ID == 0 {
+		db.metadata.LastScheduleID++
+		schedule.ID = db.metadata.LastScheduleID
+		if err := db.saveMetadata(); err != nil {
+			return err
+		}
+		schedules = append(schedules, *schedule)
+	} else {
+		for i := range schedules {
+			if schedules[i].ID == schedule.ID {
+				schedules[i] = *schedule
+			}
+		}
+	}
+
+	return db.writeSchedules(schedules)
+}
+
+func (db *FileStoreDB) GetSchedules(ctx context.Context) ([]blueberry.ScheduleInfo, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.loadSchedules()
+}
+
+func (db *FileStoreDB) DeleteSchedule(ctx context.Context, id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	schedules, err := db.loadSchedules()
+	if err != nil {
+		return err
+	}
+
+	updatedSchedules := make([]blueberry.ScheduleInfo, 0, len(schedules))
+	for _, schedule := range schedules {
+		if schedule.ID != id {
+			updatedSchedules = append(updatedSchedules, schedule)
+		}
+	}
+
+	return db.writeSchedules(updatedSchedules)
+}
+
 func (db *FileStoreDB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
 	return db.saveMetadata()
 }
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"errors"
 
 	blueberry "github.com/ersauravadhikari/blueberry-go/blueberry"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,10 +12,19 @@ import (
 
 // MongoDB is a struct that provides MongoDB client and collections for task management.
 type MongoDB struct {
-	client      *mongo.Client
-	database    *mongo.Database
-	taskRuns    *mongo.Collection
-	taskRunLogs *mongo.Collection
+	client          *mongo.Client
+	database        *mongo.Database
+	taskRuns        *mongo.Collection
+	taskRunLogs     *mongo.Collection
+	schedules       *mongo.Collection
+	idempotencyKeys *mongo.Collection
+}
+
+// idempotencyKey is the document of a run saved for an idempotency key it was not started with
+type idempotencyKey struct {
+	TaskName       string `bson:"taskname"`
+	IdempotencyKey string `bson:"idempotencykey"`
+	TaskRunID      int    `bson:"taskrunid"`
 }
 
 // NewMongoDB initializes a new MongoDB instance, connects to the database, and sets up collections and indexes.
@@ -28,16 +38,19 @@ func NewMongoDB(uri, dbName string) (*MongoDB, error) {
 	db := client.Database(dbName)
 	taskRuns := db.Collection("task_runs")
 	taskRunLogs := db.Collection("task_run_logs")
+	schedules := db.Collection("schedules")
 
 	// Initialize MongoDB instance
 	mongoDB := &MongoDB{
-		client:      client,
-		database:    db,
-		taskRuns:    taskRuns,
-		taskRunLogs: taskRunLogs,
+		client:          client,
+		database:        db,
+		taskRuns:        taskRuns,
+		taskRunLogs:     taskRunLogs,
+		schedules:       schedules,
+		idempotencyKeys: db.Collection("idempotency_keys"),
 	}
 
-	// Initialize counters for taskRunID and taskRunLogID
+	// Initialize counters for taskRunID, taskRunLogID and scheduleID
 	if err := mongoDB.InitializeCounters(context.Background()); err != nil {
 		return nil, err
 	}
@@ -50,12 +63,15 @@ func NewMongoDB(uri, dbName string) (*MongoDB, error) {
 	return mongoDB, nil
 }
 
-// InitializeCounters initializes necessary counters for the task run and log ID sequences.
+// InitializeCounters initializes necessary counters for the task run, log and schedule ID sequences.
 func (db *MongoDB) InitializeCounters(ctx context.Context) error {
 	if err := db.ensureCounter(ctx, "taskRunID"); err != nil {
 		return err
 	}
-	return db.ensureCounter(ctx, "taskRunLogID")
+	if err := db.ensureCounter(ctx, "taskRunLogID"); err != nil {
+		return err
+	}
+	return db.ensureCounter(ctx, "scheduleID")
 }
 
 // ensureCounter checks if a counter document exists, initializing it if missing.
@@ -82,6 +98,39 @@ func (db *MongoDB) createIndexes() error {
 		return err
 	}
 
+	// Index for task_runs collection on 'taskname' and 'idempotencykey'
+	idempotencyIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "taskname", Value: 1},
+			{Key: "idempotencykey", Value: 1},
+		},
+		Options: options.Index().SetBackground(true),
+	}
+	if _, err := db.taskRuns.Indexes().CreateOne(context.Background(), idempotencyIndex); err != nil {
+		return err
+	}
+
+	// Unique index for idempotency_keys collection on 'taskname' and 'idempotencykey'
+	savedKeyIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "taskname", Value: 1},
+			{Key: "idempotencykey", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetBackground(true),
+	}
+	if _, err := db.idempotencyKeys.Indexes().CreateOne(context.Background(), savedKeyIndex); err != nil {
+		return err
+	}
+
+	// Index for task_runs collection on 'status', used to restore runs on start
+	statusIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "status", Value: 1}},
+		Options: options.Index().SetBackground(true),
+	}
+	if _, err := db.taskRuns.Indexes().CreateOne(context.Background(), statusIndex); err != nil {
+		return err
+	}
+
 	// Index for task_run_logs collection on 'taskrunid' and 'level'
 	taskRunLogIndex := mongo.IndexModel{
 		Keys: bson.D{
@@ -165,6 +214,25 @@ func (db *MongoDB) GetTaskRuns(ctx context.Context) ([]blueberry.TaskRun, error)
 	return taskRuns, nil
 }
 
+// GetTaskRunsByStatus retrieves the task runs with the given status, oldest first.
+func (db *MongoDB) GetTaskRunsByStatus(ctx context.Context, status string) ([]blueberry.TaskRun, error) {
+	cursor, err := db.taskRuns.Find(ctx, bson.M{"status": status}, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var taskRuns []blueberry.TaskRun
+	for cursor.Next(ctx) {
+		var taskRun blueberry.TaskRun
+		if err := cursor.Decode(&taskRun); err != nil {
+			return nil, err
+		}
+		taskRuns = append(taskRuns, taskRun)
+	}
+	return taskRuns, cursor.Err()
+}
+
 // GetPaginatedTaskRunsForTaskName retrieves task runs for a specific task, paginated and sorted by start time.
 func (db *MongoDB) GetPaginatedTaskRunsForTaskName(ctx context.Context, name string, page, limit int) ([]blueberry.TaskRun, error) {
 	skip := (page - 1) * limit
@@ -256,6 +324,83 @@ func (db *MongoDB) GetTaskRunByID(ctx context.Context, id int) (*blueberry.TaskR
 	return &taskRun, nil
 }
 
+// GetTaskRunByIdempotencyKey retrieves the most recent task run of a task stored or saved with the idempotency key, nil if there is none.
+func (db *MongoDB) GetTaskRunByIdempotencyKey(ctx context.Context, taskName, key string) (*blueberry.TaskRun, error) {
+	var saved idempotencyKey
+	err := db.idempotencyKeys.FindOne(ctx, bson.M{"taskname": taskName, "idempotencykey": key}).Decode(&saved)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	filter := bson.M{"taskname": taskName, "$or": bson.A{
+		bson.M{"idempotencykey": key},
+		bson.M{"id": saved.TaskRunID},
+	}}
+	var taskRun blueberry.TaskRun
+	err = db.taskRuns.FindOne(ctx, filter, options.FindOne().SetSort(bson.M{"id": -1})).Decode(&taskRun)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &taskRun, nil
+}
+
+// SaveIdempotencyKey makes the idempotency key of the task return a run that was started without it.
+func (db *MongoDB) SaveIdempotencyKey(ctx context.Context, taskName, key string, taskRunID int) error {
+	filter := bson.M{"taskname": taskName, "idempotencykey": key}
+	update := bson.M{"$set": idempotencyKey{TaskName: taskName, IdempotencyKey: key, TaskRunID: taskRunID}}
+	_, err := db.idempotencyKeys.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// SaveSchedule inserts a new schedule document or updates an existing one.
+func (db *MongoDB) SaveSchedule(ctx context.Context, schedule *blueberry.ScheduleInfo) error {
+	if schedule.ID == 0 {
+		nextID, err := db.GetNextSequence(ctx, "scheduleID")
+		if err != nil {
+			return err
+		}
+		schedule.ID = nextID
+
+		// Insert new schedule
+		_, err = db.schedules.InsertOne(ctx, schedule)
+		return err
+	}
+
+	// Update existing schedule
+	filter := bson.M{"id": schedule.ID}
+	update := bson.M{"$set": schedule}
+	_, err := db.schedules.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// GetSchedules retrieves all persisted schedules.
+func (db *MongoDB) GetSchedules(ctx context.Context) ([]blueberry.ScheduleInfo, error) {
+	cursor, err := db.schedules.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var schedules []blueberry.ScheduleInfo
+	for cursor.Next(ctx) {
+		var schedule blueberry.ScheduleInfo
+		if err := cursor.Decode(&schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// DeleteSchedule removes a persisted schedule by ID.
+func (db *MongoDB) DeleteSchedule(ctx context.Context, id int) error {
+	_, err := db.schedules.DeleteOne(ctx, bson.M{"id": id})
+	return err
+}
+
 // Close disconnects the MongoDB client.
 func (db *MongoDB) Close() error {
 	return db.client.Disconnect(context.Background())
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	blueberry "github.com/ersauravadhikari/blueberry-go/blueberry"
 	"github.com/jackc/pgx/v4"
 )
@@ -44,6 +45,38 @@ func (db *PostgresDB) migrate() error {
 		message TEXT,
 		FOREIGN KEY (task_run_id) REFERENCES task_runs(id)
 	);
+
+	CREATE TABLE IF NOT EXISTS schedules (
+		id SERIAL PRIMARY KEY,
+		task_name VARCHAR(255),
+		schedule VARCHAR(255),
+		params JSONB
+	);
+
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		task_name VARCHAR(255),
+		idempotency_key VARCHAR(255),
+		task_run_id INTEGER,
+		PRIMARY KEY (task_name, idempotency_key),
+		FOREIGN KEY (task_run_id) REFERENCES task_runs(id)
+	);
+
+	ALTER TABLE schedules ADD COLUMN IF NOT EXISTS paused BOOLEAN DEFAULT FALSE;
+	ALTER TABLE schedules ADD COLUMN IF NOT EXISTS options JSONB;
+	ALTER TABLE schedules ADD COLUMN IF NOT EXISTS last_fired BIGINT DEFAULT 0;
+	ALTER TABLE schedules ADD COLUMN IF NOT EXISTS occurrences INTEGER DEFAULT 0;
+	ALTER TABLE schedules ADD COLUMN IF NOT EXISTS origin VARCHAR(50) DEFAULT '';
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS catch_up BOOLEAN DEFAULT FALSE;
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS run_at TIMESTAMP;
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS attempt INTEGER DEFAULT 1;
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS retry_of INTEGER DEFAULT 0;
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS priority INTEGER DEFAULT 0;
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS schedule_id INTEGER DEFAULT 0;
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS timeout BIGINT DEFAULT 0;
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255) DEFAULT '';
+	ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS result JSONB;
+	CREATE INDEX IF NOT EXISTS idx_task_runs_idempotency_key ON task_runs (task_name, idempotency_key);
+	CREATE INDEX IF NOT EXISTS idx_task_runs_status ON task_runs (status);
 	`
 
 	_, err := db.conn.Exec(context.Background(), query)
@@ -54,12 +87,12 @@ func (db *PostgresDB) SaveTaskRun(ctx context.Context, taskRun *blueberry.TaskRu
 	params, _ := json.Marshal(taskRun.Params)
 	if taskRun.ID == 0 {
 		return db.conn.QueryRow(ctx,
-			"INSERT INTO task_runs (task_name, start_time, end_time, params, status) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-			taskRun.TaskName, taskRun.StartTime, taskRun.EndTime, params, taskRun.Status).Scan(&taskRun.ID)
+			"INSERT INTO task_runs (task_name, start_time, end_time, params, status, catch_up, run_at, attempt, retry_of, priority, schedule_id, timeout, idempotency_key, result) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id",
+			taskRun.TaskName, taskRun.StartTime, taskRun.EndTime, params, taskRun.Status, taskRun.CatchUp, taskRun.RunAt, taskRun.Attempt, taskRun.RetryOf, taskRun.Priority, taskRun.ScheduleID, taskRun.Timeout, taskRun.IdempotencyKey, nullableJSON(taskRun.Result)).Scan(&taskRun.ID)
 	} else {
 		_, err := db.conn.Exec(ctx,
-			"UPDATE task_runs SET task_name = $1, start_time = $2, end_time = $3, params = $4, status = $5 WHERE id = $6",
-			taskRun.TaskName, taskRun.StartTime, taskRun.EndTime, params, taskRun.Status, taskRun.ID)
+			"UPDATE task_runs SET task_name = $1, start_time = $2, end_time = $3, params = $4, status = $5, catch_up = $6, run_at = $7, attempt = $8, retry_of = $9, priority = $10, schedule_id = $11, timeout = $12, idempotency_key = $13, result = $14 WHERE id = $15",
+			taskRun.TaskName, taskRun.StartTime, taskRun.EndTime, params, taskRun.Status, taskRun.CatchUp, taskRun.RunAt, taskRun.Attempt, taskRun.RetryOf, taskRun.Priority, taskRun.ScheduleID, taskRun.Timeout, taskRun.IdempotencyKey, nullableJSON(taskRun.Result), taskRun.ID)
 		return err
 	}
 }
@@ -71,7 +104,7 @@ func (db *PostgresDB) SaveTaskRunLog(ctx context.Context, taskRunLog *blueberry.
 }
 
 func (db *PostgresDB) GetTaskRuns(ctx context.Context) ([]blueberry.TaskRun, error) {
-	rows, err := db.conn.Query(ctx, "SELECT id, task_name, start_time, end_time, params, status FROM task_runs")
+	rows, err := db.conn.Query(ctx, "SELECT "+taskRunColumns+" FROM task_runs")
 	if err != nil {
 		return nil, err
 	}
@@ -79,20 +112,37 @@ func (db *PostgresDB) GetTaskRuns(ctx context.Context) ([]blueberry.TaskRun, err
 
 	var taskRuns []blueberry.TaskRun
 	for rows.Next() {
-		var taskRun blueberry.TaskRun
-		var params []byte
-		if err := rows.Scan(&taskRun.ID, &taskRun.TaskName, &taskRun.StartTime, &taskRun.EndTime, &params, &taskRun.Status); err != nil {
+		taskRun, err := scanTaskRun(rows)
+		if err != nil {
 			return nil, err
 		}
-		json.Unmarshal(params, &taskRun.Params)
 		taskRuns = append(taskRuns, taskRun)
 	}
 	return taskRuns, nil
 }
 
+// GetTaskRunsByStatus returns the runs with the given status, oldest first
+func (db *PostgresDB) GetTaskRunsByStatus(ctx context.Context, status string) ([]blueberry.TaskRun, error) {
+	rows, err := db.conn.Query(ctx, "SELECT "+taskRunColumns+" FROM task_runs WHERE status = $1 ORDER BY id", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var taskRuns []blueberry.TaskRun
+	for rows.Next() {
+		taskRun, err := scanTaskRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		taskRuns = append(taskRuns, taskRun)
+	}
+	return taskRuns, rows.Err()
+}
+
 func (db *PostgresDB) GetPaginatedTaskRunsForTaskName(ctx context.Context, name string, page, limit int) ([]blueberry.TaskRun, error) {
 	offset := (page - 1) * limit
-	rows, err := db.conn.Query(ctx, "SELECT id, task_name, start_time, end_time, params, status FROM task_runs WHERE task_name = $1 ORDER BY start_time DESC LIMIT $2 OFFSET $3", name, limit, offset)
+	rows, err := db.conn.Query(ctx, "SELECT "+taskRunColumns+" FROM task_runs WHERE task_name = $1 ORDER BY start_time DESC LIMIT $2 OFFSET $3", name, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -100,12 +150,10 @@ func (db *PostgresDB) GetPaginatedTaskRunsForTaskName(ctx context.Context, name
 
 	var taskRuns []blueberry.TaskRun
 	for rows.Next() {
-		var taskRun blueberry.TaskRun
-		var params []byte
-		if err := rows.Scan(&taskRun.ID, &taskRun.TaskName, &taskRun.StartTime, &taskRun.EndTime, &params, &taskRun.Status); err != nil {
+		taskRun, err := scanTaskRun(rows)
+		if err != nil {
 			return nil, err
 		}
-		json.Unmarshal(params, &taskRun.Params)
 		taskRuns = append(taskRuns, taskRun)
 	}
 	return taskRuns, nil
@@ -182,18 +230,79 @@ func (db *PostgresDB) GetPaginatedTaskRunLogs(ctx context.Context, taskRunID int
 }
 
 func (db *PostgresDB) GetTaskRunByID(ctx context.Context, id int) (*blueberry.TaskRun, error) {
-	row := db.conn.QueryRow(ctx, "SELECT id, task_name, start_time, end_time, params, status FROM task_runs WHERE id = $1", id)
-	var taskRun blueberry.TaskRun
-	var params []byte
-	if err := row.Scan(&taskRun.ID, &taskRun.TaskName, &taskRun.StartTime, &taskRun.EndTime, &params, &taskRun.Status); err != nil {
+	row := db.conn.QueryRow(ctx, "SELECT "+taskRunColumns+" FROM task_runs WHERE id = $1", id)
+	taskRun, err := scanTaskRun(row)
+	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(params, &taskRun.Params); err != nil {
+	return &taskRun, nil
+}
+
+// GetTaskRunByIdempotencyKey returns the most recent run of the task stored or saved with the idempotency key, nil if there is none
+func (db *PostgresDB) GetTaskRunByIdempotencyKey(ctx context.Context, taskName, key string) (*blueberry.TaskRun, error) {
+	row := db.conn.QueryRow(ctx, "SELECT "+taskRunColumns+" FROM task_runs WHERE task_name = $1 AND (idempotency_key = $2 OR id IN (SELECT task_run_id FROM idempotency_keys WHERE task_name = $1 AND idempotency_key = $2)) ORDER BY id DESC LIMIT 1",
+		taskName, key)
+	taskRun, err := scanTaskRun(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &taskRun, nil
 }
 
+// SaveIdempotencyKey makes the idempotency key of the task return a run that was started without it
+func (db *PostgresDB) SaveIdempotencyKey(ctx context.Context, taskName, key string, taskRunID int) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO idempotency_keys (task_name, idempotency_key, task_run_id) VALUES ($1, $2, $3) ON CONFLICT (task_name, idempotency_key) DO UPDATE SET task_run_id = EXCLUDED.task_run_id",
+		taskName, key, taskRunID)
+	return err
+}
+
+func (db *PostgresDB) SaveSchedule(ctx context.Context, schedule *blueberry.ScheduleInfo) error {
+	params, _ := json.Marshal(schedule.Params)
+	options, _ := json.Marshal(schedule.ScheduleOptions)
+	if schedule.ID == 0 {
+		return db.conn.QueryRow(ctx,
+			"INSERT INTO schedules (task_name, schedule, params, paused, options, last_fired, occurrences, origin) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+			schedule.TaskName, schedule.Schedule, params, schedule.Paused, options, schedule.LastFired, schedule.Occurrences, schedule.Origin).Scan(&schedule.ID)
+	} else {
+		_, err := db.conn.Exec(ctx,
+			"UPDATE schedules SET task_name = $1, schedule = $2, params = $3, paused = $4, options = $5, last_fired = $6, occurrences = $7, origin = $8 WHERE id = $9",
+			schedule.TaskName, schedule.Schedule, params, schedule.Paused, options, schedule.LastFired, schedule.Occurrences, schedule.Origin, schedule.ID)
+		return err
+	}
+}
+
+func (db *PostgresDB) GetSchedules(ctx context.Context) ([]blueberry.ScheduleInfo, error) {
+	rows, err := db.conn.Query(ctx, "SELECT id, task_name, schedule, params, paused, options, last_fired, occurrences, origin FROM schedules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []blueberry.ScheduleInfo
+	for rows.Next() {
+		var schedule blueberry.ScheduleInfo
+		var params, options []byte
+		if err := rows.Scan(&schedule.ID, &schedule.TaskName, &schedule.Schedule, &params, &schedule.Paused, &options, &schedule.LastFired, &schedule.Occurrences, &schedule.Origin); err != nil {
+			return nil, err
+		}
+		json.Unmarshal(params, &schedule.Params)
+		if len(options) > 0 {
+			json.Unmarshal(options, &schedule.ScheduleOptions)
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+func (db *PostgresDB) DeleteSchedule(ctx context.Context, id int) error {
+	_, err := db.conn.Exec(ctx, "DELETE FROM schedules WHERE id = $1", id)
+	return err
+}
+
 func (db *PostgresDB) Close() error {
 	return db.conn.Close(context.Background())
 }
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	blueberry "github.com/ersauravadhikari/blueberry-go/blueberry"
+)
+
+// taskRunColumns are the task_runs columns read by scanTaskRun, in scan order
+const taskRunColumns = "id, task_name, start_time, end_time, params, status, catch_up, run_at, attempt, retry_of, priority, schedule_id, timeout, idempotency_key, result"
+
+// nullableJSON stores an empty JSON value as NULL
+func nullableJSON(value json.RawMessage) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return string(value)
+}
+
+// rowScanner is implemented by the rows returned by both database/sql and pgx
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTaskRun reads a task run selected with taskRunColumns
+func scanTaskRun(row rowScanner) (blueberry.TaskRun, error) {
+	var taskRun blueberry.TaskRun
+	var params []byte
+	var runAt sql.NullTime // NULL for runs stored before delayed runs existed
+	var result []byte      // NULL for runs without a result
+	if err := row.Scan(&taskRun.ID, &taskRun.TaskName, &taskRun.StartTime, &taskRun.EndTime, &params, &taskRun.Status, &taskRun.CatchUp, &runAt, &taskRun.Attempt, &taskRun.RetryOf, &taskRun.Priority, &taskRun.ScheduleID, &taskRun.Timeout, &taskRun.IdempotencyKey, &result); err != nil {
+		return blueberry.TaskRun{}, err
+	}
+	taskRun.RunAt = runAt.Time
+	if len(result) > 0 {
+		taskRun.Result = json.RawMessage(result)
+	}
+	if err := json.Unmarshal(params, &taskRun.Params); err != nil {
+		return blueberry.TaskRun{}, err
+	}
+	return taskRun, nil
+}
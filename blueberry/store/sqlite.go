@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	blueberry "github.com/ersauravadhikari/blueberry-go/blueberry"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -45,9 +47,87 @@ func (db *SQLiteDB) migrate() error {
 		message TEXT,
 		FOREIGN KEY (task_run_id) REFERENCES task_runs(id)
 	);
+
+	CREATE TABLE IF NOT EXISTS schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_name TEXT,
+		schedule TEXT,
+		params TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		task_name TEXT,
+		idempotency_key TEXT,
+		task_run_id INTEGER,
+		PRIMARY KEY (task_name, idempotency_key),
+		FOREIGN KEY (task_run_id) REFERENCES task_runs(id)
+	);
 	`
 
-	_, err := db.conn.Exec(query)
+	if _, err := db.conn.Exec(query); err != nil {
+		return err
+	}
+
+	// Columns added after the tables were first released
+	columns := []struct {
+		table, column, definition string
+	}{
+		{"schedules", "paused", "BOOLEAN DEFAULT 0"},
+		{"schedules", "options", "TEXT"},
+		{"schedules", "last_fired", "INTEGER DEFAULT 0"},
+		{"schedules", "occurrences", "INTEGER DEFAULT 0"},
+		{"schedules", "origin", "TEXT DEFAULT ''"},
+		{"task_runs", "catch_up", "BOOLEAN DEFAULT 0"},
+		{"task_runs", "run_at", "TIMESTAMP"},
+		{"task_runs", "attempt", "INTEGER DEFAULT 1"},
+		{"task_runs", "retry_of", "INTEGER DEFAULT 0"},
+		{"task_runs", "priority", "INTEGER DEFAULT 0"},
+		{"task_runs", "schedule_id", "INTEGER DEFAULT 0"},
+		{"task_runs", "timeout", "INTEGER DEFAULT 0"},
+		{"task_runs", "idempotency_key", "TEXT DEFAULT ''"},
+		{"task_runs", "result", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := db.addColumnIfMissing(c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	// Needs the idempotency_key column, so it is created after the migration
+	if _, err := db.conn.Exec("CREATE INDEX IF NOT EXISTS idx_task_runs_idempotency_key ON task_runs (task_name, idempotency_key)"); err != nil {
+		return err
+	}
+
+	_, err := db.conn.Exec("CREATE INDEX IF NOT EXISTS idx_task_runs_status ON task_runs (status)")
+	return err
+}
+
+// addColumnIfMissing adds a column to an existing table, SQLite has no ADD COLUMN IF NOT EXISTS
+func (db *SQLiteDB) addColumnIfMissing(table, column, definition string) error {
+	rows, err := db.conn.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
@@ -55,8 +135,8 @@ func (db *SQLiteDB) SaveTaskRun(ctx context.Context, taskRun *blueberry.TaskRun)
 	params, _ := json.Marshal(taskRun.Params)
 	if taskRun.ID == 0 {
 		result, err := db.conn.ExecContext(ctx,
-			"INSERT INTO task_runs (task_name, start_time, end_time, params, status) VALUES (?, ?, ?, ?, ?)",
-			taskRun.TaskName, taskRun.StartTime, taskRun.EndTime, params, taskRun.Status)
+			"INSERT INTO task_runs (task_name, start_time, end_time, params, status, catch_up, run_at, attempt, retry_of, priority, schedule_id, timeout, idempotency_key, result) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			taskRun.TaskName, taskRun.StartTime, taskRun.EndTime, params, taskRun.Status, taskRun.CatchUp, taskRun.RunAt, taskRun.Attempt, taskRun.RetryOf, taskRun.Priority, taskRun.ScheduleID, taskRun.Timeout, taskRun.IdempotencyKey, nullableJSON(taskRun.Result))
 		if err != nil {
 			return err
 		}
@@ -67,8 +147,8 @@ func (db *SQLiteDB) SaveTaskRun(ctx context.Context, taskRun *blueberry.TaskRun)
 		taskRun.ID = int(id)
 	} else {
 		_, err := db.conn.ExecContext(ctx,
-			"UPDATE task_runs SET task_name = ?, start_time = ?, end_time = ?, params = ?, status = ? WHERE id = ?",
-			taskRun.TaskName, taskRun.StartTime, taskRun.EndTime, params, taskRun.Status, taskRun.ID)
+			"UPDATE task_runs SET task_name = ?, start_time = ?, end_time = ?, params = ?, status = ?, catch_up = ?, run_at = ?, attempt = ?, retry_of = ?, priority = ?, schedule_id = ?, timeout = ?, idempotency_key = ?, result = ? WHERE id = ?",
+			taskRun.TaskName, taskRun.StartTime, taskRun.EndTime, params, taskRun.Status, taskRun.CatchUp, taskRun.RunAt, taskRun.Attempt, taskRun.RetryOf, taskRun.Priority, taskRun.ScheduleID, taskRun.Timeout, taskRun.IdempotencyKey, nullableJSON(taskRun.Result), taskRun.ID)
 		if err != nil {
 			return err
 		}
@@ -77,18 +157,36 @@ func (db *SQLiteDB) SaveTaskRun(ctx context.Context, taskRun *blueberry.TaskRun)
 }
 
 func (db *SQLiteDB) GetTaskRunByID(ctx context.Context, id int) (*blueberry.TaskRun, error) {
-	row := db.conn.QueryRowContext(ctx, "SELECT id, task_name, start_time, end_time, params, status FROM task_runs WHERE id = ?", id)
-	var taskRun blueberry.TaskRun
-	var params []byte
-	if err := row.Scan(&taskRun.ID, &taskRun.TaskName, &taskRun.StartTime, &taskRun.EndTime, &params, &taskRun.Status); err != nil {
+	row := db.conn.QueryRowContext(ctx, "SELECT "+taskRunColumns+" FROM task_runs WHERE id = ?", id)
+	taskRun, err := scanTaskRun(row)
+	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(params, &taskRun.Params); err != nil {
+	return &taskRun, nil
+}
+
+// GetTaskRunByIdempotencyKey returns the most recent run of the task stored or saved with the idempotency key, nil if there is none
+func (db *SQLiteDB) GetTaskRunByIdempotencyKey(ctx context.Context, taskName, key string) (*blueberry.TaskRun, error) {
+	row := db.conn.QueryRowContext(ctx, "SELECT "+taskRunColumns+" FROM task_runs WHERE task_name = ? AND (idempotency_key = ? OR id IN (SELECT task_run_id FROM idempotency_keys WHERE task_name = ? AND idempotency_key = ?)) ORDER BY id DESC LIMIT 1",
+		taskName, key, taskName, key)
+	taskRun, err := scanTaskRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &taskRun, nil
 }
 
+// SaveIdempotencyKey makes the idempotency key of the task return a run that was started without it
+func (db *SQLiteDB) SaveIdempotencyKey(ctx context.Context, taskName, key string, taskRunID int) error {
+	_, err := db.conn.ExecContext(ctx,
+		"INSERT INTO idempotency_keys (task_name, idempotency_key, task_run_id) VALUES (?, ?, ?) ON CONFLICT (task_name, idempotency_key) DO UPDATE SET task_run_id = excluded.task_run_id",
+		taskName, key, taskRunID)
+	return err
+}
+
 func (db *SQLiteDB) SaveTaskRunLog(ctx context.Context, taskRunLog *blueberry.TaskRunLog) error {
 	result, err := db.conn.ExecContext(ctx,
 		"INSERT INTO task_run_logs (task_run_id, timestamp, level, message) VALUES (?, ?, ?, ?)",
@@ -105,7 +203,7 @@ func (db *SQLiteDB) SaveTaskRunLog(ctx context.Context, taskRunLog *blueberry.Ta
 }
 
 func (db *SQLiteDB) GetTaskRuns(ctx context.Context) ([]blueberry.TaskRun, error) {
-	rows, err := db.conn.QueryContext(ctx, "SELECT id, task_name, start_time, end_time, params, status FROM task_runs ORDER BY start_time DESC")
+	rows, err := db.conn.QueryContext(ctx, "SELECT "+taskRunColumns+" FROM task_runs ORDER BY start_time DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -113,12 +211,10 @@ func (db *SQLiteDB) GetTaskRuns(ctx context.Context) ([]blueberry.TaskRun, error
 
 	var taskRuns []blueberry.TaskRun
 	for rows.Next() {
-		var taskRun blueberry.TaskRun
-		var params []byte
-		if err := rows.Scan(&taskRun.ID, &taskRun.TaskName, &taskRun.StartTime, &taskRun.EndTime, &params, &taskRun.Status); err != nil {
+		taskRun, err := scanTaskRun(rows)
+		if err != nil {
 			return nil, err
 		}
-		json.Unmarshal(params, &taskRun.Params)
 		taskRuns = append(taskRuns, taskRun)
 	}
 
@@ -129,9 +225,33 @@ func (db *SQLiteDB) GetTaskRuns(ctx context.Context) ([]blueberry.TaskRun, error
 	return taskRuns, nil
 }
 
+// GetTaskRunsByStatus returns the runs with the given status, oldest first
+func (db *SQLiteDB) GetTaskRunsByStatus(ctx context.Context, status string) ([]blueberry.TaskRun, error) {
+	rows, err := db.conn.QueryContext(ctx, "SELECT "+taskRunColumns+" FROM task_runs WHERE status = ? ORDER BY id", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var taskRuns []blueberry.TaskRun
+	for rows.Next() {
+		taskRun, err := scanTaskRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		taskRuns = append(taskRuns, taskRun)
+	}
+
+	if taskRuns == nil {
+		return []blueberry.TaskRun{}, nil
+	}
+
+	return taskRuns, rows.Err()
+}
+
 func (db *SQLiteDB) GetPaginatedTaskRunsForTaskName(ctx context.Context, name string, page, limit int) ([]blueberry.TaskRun, error) {
 	offset := (page - 1) * limit
-	rows, err := db.conn.QueryContext(ctx, "SELECT id, task_name, start_time, end_time, params, status FROM task_runs WHERE task_name = ? ORDER BY start_time DESC LIMIT ? OFFSET ?", name, limit, offset)
+	rows, err := db.conn.QueryContext(ctx, "SELECT "+taskRunColumns+" FROM task_runs WHERE task_name = ? ORDER BY start_time DESC LIMIT ? OFFSET ?", name, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -139,12 +259,10 @@ func (db *SQLiteDB) GetPaginatedTaskRunsForTaskName(ctx context.Context, name st
 
 	var taskRuns []blueberry.TaskRun
 	for rows.Next() {
-		var taskRun blueberry.TaskRun
-		var params []byte
-		if err := rows.Scan(&taskRun.ID, &taskRun.TaskName, &taskRun.StartTime, &taskRun.EndTime, &params, &taskRun.Status); err != nil {
+		taskRun, err := scanTaskRun(rows)
+		if err != nil {
 			return nil, err
 		}
-		json.Unmarshal(params, &taskRun.Params)
 		taskRuns = append(taskRuns, taskRun)
 	}
 
@@ -230,6 +348,65 @@ func (db *SQLiteDB) GetPaginatedTaskRunLogs(ctx context.Context, taskRunID int,
 	return taskRunLogs, totalCount, nil
 }
 
+func (db *SQLiteDB) SaveSchedule(ctx context.Context, schedule *blueberry.ScheduleInfo) error {
+	params, _ := json.Marshal(schedule.Params)
+	options, _ := json.Marshal(schedule.ScheduleOptions)
+	if schedule.ID == 0 {
+		result, err := db.conn.ExecContext(ctx,
+			"INSERT INTO schedules (task_name, schedule, params, paused, options, last_fired, occurrences, origin) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			schedule.TaskName, schedule.Schedule, params, schedule.Paused, options, schedule.LastFired, schedule.Occurrences, schedule.Origin)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		schedule.ID = int(id)
+	} else {
+		_, err := db.conn.ExecContext(ctx,
+			"UPDATE schedules SET task_name = ?, schedule = ?, params = ?, paused = ?, options = ?, last_fired = ?, occurrences = ?, origin = ? WHERE id = ?",
+			schedule.TaskName, schedule.Schedule, params, schedule.Paused, options, schedule.LastFired, schedule.Occurrences, schedule.Origin, schedule.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *SQLiteDB) GetSchedules(ctx context.Context) ([]blueberry.ScheduleInfo, error) {
+	rows, err := db.conn.QueryContext(ctx, "SELECT id, task_name, schedule, params, paused, options, last_fired, occurrences, origin FROM schedules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []blueberry.ScheduleInfo
+	for rows.Next() {
+		var schedule blueberry.ScheduleInfo
+		var params, options []byte
+		if err := rows.Scan(&schedule.ID, &schedule.TaskName, &schedule.Schedule, &params, &schedule.Paused, &options, &schedule.LastFired, &schedule.Occurrences, &schedule.Origin); err != nil {
+			return nil, err
+		}
+		json.Unmarshal(params, &schedule.Params)
+		if len(options) > 0 {
+			json.Unmarshal(options, &schedule.ScheduleOptions)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if schedules == nil {
+		return []blueberry.ScheduleInfo{}, nil
+	}
+
+	return schedules, nil
+}
+
+func (db *SQLiteDB) DeleteSchedule(ctx context.Context, id int) error {
+	_, err := db.conn.ExecContext(ctx, "DELETE FROM schedules WHERE id = ?", id)
+	return err
+}
+
 func (db *SQLiteDB) Close() error {
 	return db.conn.Close()
 }
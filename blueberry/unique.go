@@ -0,0 +1,89 @@
+package blueberry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrAlreadyRunning is matched by errors.Is for every *AlreadyRunningError
+var ErrAlreadyRunning = errors.New("already running")
+
+// UniquePolicy decides what happens when a run of a task is started while a run with the same parameters is queued
+// or running
+type UniquePolicy string
+
+const (
+	UniqueAllow  UniquePolicy = "allow"  // Start the run next to the existing one (default)
+	UniqueReject UniquePolicy = "reject" // Do not start the run, ExecuteNow returns an *AlreadyRunningError
+	UniqueReuse  UniquePolicy = "reuse"  // Do not start the run, ExecuteNow returns the run ID of the existing run
+)
+
+func (p UniquePolicy) validate() error {
+	switch p {
+	case "", UniqueAllow, UniqueReject, UniqueReuse:
+		return nil
+	}
+	return fmt.Errorf("unknown unique policy %q", p)
+}
+
+// AlreadyRunningError is returned when a run is not started because a run of the task with the same parameters is
+// queued or running
+type AlreadyRunningError struct {
+	TaskName string
+	RunID    int // Run that is queued or running
+}
+
+func (e *AlreadyRunningError) Error() string {
+	return fmt.Sprintf("task %s already has run %d with the same parameters queued or running", e.TaskName, e.RunID)
+}
+
+func (e *AlreadyRunningError) Is(target error) bool {
+	return target == ErrAlreadyRunning
+}
+
+// unique reports whether runs of the task are deduplicated by their parameters
+func (t *Task) unique() bool {
+	return t.options.Unique == UniqueReject || t.options.Unique == UniqueReuse
+}
+
+// uniqueKey identifies the parameters compared by the unique policy of the task, empty when runs are not
+// deduplicated. Maps are encoded with sorted keys, so equal parameters give equal keys.
+func (t *Task) uniqueKey(params TaskParams) string {
+	if !t.unique() {
+		return ""
+	}
+
+	compared := map[string]interface{}(params)
+	if len(t.options.UniqueKeys) > 0 {
+		compared = make(map[string]interface{}, len(t.options.UniqueKeys))
+		for _, key := range t.options.UniqueKeys {
+			compared[key] = params[key]
+		}
+	}
+	key, _ := json.Marshal(compared)
+	return string(key)
+}
+
+// duplicateRun returns the queued or running run of the task with the given unique key, 0 if there is none
+func (r *BlueBerry) duplicateRun(taskName, uniqueKey string) int {
+	r.queueMux.Lock()
+	for _, queued := range r.queue {
+		if queued.exec.taskName == taskName && queued.exec.uniqueKey == uniqueKey {
+			r.queueMux.Unlock()
+			return queued.exec.runID
+		}
+	}
+	r.queueMux.Unlock()
+
+	runID := 0
+	r.executing.Range(func(_, value interface{}) bool {
+		exec := value.(*execution)
+		if exec.taskName == taskName && exec.uniqueKey == uniqueKey {
+			runID = exec.runID
+			return false
+		}
+		return true
+	})
+	return runID
+}
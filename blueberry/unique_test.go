@@ -0,0 +1,114 @@
+package blueberry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUniquePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     UniquePolicy
+		delayed    bool   // The duplicate is started through ExecuteAfter
+		wantErr    bool   // Starting the duplicate returns an *AlreadyRunningError
+		wantReuse  bool   // Starting the duplicate returns the ID of the running run
+		wantStatus string // Status of the duplicate once it was due
+	}{
+		{"allow starts the duplicate", UniqueAllow, false, false, false, "started"},
+		{"reject refuses the duplicate", UniqueReject, false, true, false, ""},
+		{"reuse returns the running run", UniqueReuse, false, false, true, ""},
+		{"allow starts a delayed duplicate", UniqueAllow, true, false, false, "started"},
+		{"reject skips a delayed duplicate", UniqueReject, true, false, false, "skipped"},
+		{"reuse skips a delayed duplicate", UniqueReuse, true, false, false, "skipped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			rb := newTestInstance(t, db, Options{})
+			task, err := rb.RegisterTaskWithOptions("import", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+				<-ctx.Done()
+				return nil
+			}, versionSchema, TaskOptions{Unique: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			running, err := task.ExecuteNow(TaskParams{"version": "v1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var duplicate int
+			if tt.delayed {
+				duplicate, err = task.ExecuteAfter(TaskParams{"version": "v1"}, 10*time.Millisecond)
+			} else {
+				duplicate, err = task.ExecuteNow(TaskParams{"version": "v1"})
+			}
+			if got := errors.Is(err, ErrAlreadyRunning); got != tt.wantErr {
+				t.Fatalf("error = %v, want an *AlreadyRunningError: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+			if got := duplicate == running; got != tt.wantReuse {
+				t.Errorf("duplicate got run %d, running run is %d", duplicate, running)
+			}
+			if tt.wantStatus == "" {
+				return
+			}
+			if !waitFor(t, 3*time.Second, func() bool {
+				taskRun, err := db.GetTaskRunByID(context.Background(), duplicate)
+				return err == nil && taskRun.Status == tt.wantStatus
+			}) {
+				taskRun, _ := db.GetTaskRunByID(context.Background(), duplicate)
+				t.Errorf("duplicate has status %s, want %s", taskRun.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRestoredQueuedRunsFollowUniquePolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       UniquePolicy
+		wantStatuses []string // Statuses of the restored runs in the order they were queued
+	}{
+		{"allow restores both runs", UniqueAllow, []string{"started", "started"}},
+		{"reject skips the duplicate", UniqueReject, []string{"started", "skipped"}},
+		{"reuse skips the duplicate", UniqueReuse, []string{"started", "skipped"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			// Two runs with the same parameters were still queued when the process stopped
+			var runIDs []int
+			for i := 0; i < 2; i++ {
+				taskRun := &TaskRun{TaskName: "import", StartTime: time.Now().UTC(), Params: TaskParams{"version": "v1"}, Status: "queued", Attempt: 1}
+				if err := db.SaveTaskRun(context.Background(), taskRun); err != nil {
+					t.Fatal(err)
+				}
+				runIDs = append(runIDs, taskRun.ID)
+			}
+
+			rb := newTestInstance(t, db, Options{})
+			if _, err := rb.RegisterTaskWithOptions("import", func(ctx context.Context, _ TaskParams, _ *Logger) error {
+				<-ctx.Done()
+				return nil
+			}, versionSchema, TaskOptions{Unique: tt.policy}); err != nil {
+				t.Fatal(err)
+			}
+			rb.restoreQueuedRuns()
+
+			for i, runID := range runIDs {
+				taskRun, err := db.GetTaskRunByID(context.Background(), runID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if taskRun.Status != tt.wantStatuses[i] {
+					t.Errorf("restored run %d has status %s, want %s", runID, taskRun.Status, tt.wantStatuses[i])
+				}
+			}
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -14,10 +17,27 @@ import (
 
 // TemplateScheduleInfo is used for rendering schedules in the template
 type TemplateScheduleInfo struct {
-	Schedule               string
-	FormattedNextExecution string
-	Params                 map[string]any
-	EntryID                int
+	ID                          int
+	Schedule                    string
+	Timezone                    string
+	Overlap                     OverlapPolicy
+	Misfire                     MisfirePolicy
+	FormattedNextExecution      string
+	FormattedNextExecutionLocal string
+	Params                      map[string]any
+	Paused                      bool
+	Expired                     bool
+	FormattedStartAt            string
+	FormattedEndAt              string
+	MaxOccurrences              int
+	RemainingOccurrences        int
+	Calendar                    string
+	CalendarPolicy              CalendarPolicy
+	Jitter                      time.Duration
+	Spread                      time.Duration
+	Timeout                     time.Duration
+	Priority                    *int
+	Origin                      ScheduleOrigin
 }
 
 // TemplateTaskRun is used for rendering task runs in the template
@@ -26,6 +46,9 @@ type TemplateTaskRun struct {
 	FormattedStartTime string
 	FormattedEndTime   string
 	Status             string
+	CatchUp            bool
+	Delayed            bool
+	Attempt            int
 	Params             map[string]any
 }
 
@@ -41,6 +64,19 @@ func formatUnixTimestamp(timestamp int64) string {
 	return time.Unix(timestamp, 0).UTC().Format("2006-01-02 15:04:05")
 }
 
+// formatTimeIn formats a given time.Time to a readable string in the given location, zero times are left empty
+func formatTimeIn(t time.Time, location *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(location).Format("2006-01-02 15:04 MST")
+}
+
+// formatUnixTimestampIn formats a given Unix timestamp to a readable string in the given location
+func formatUnixTimestampIn(timestamp int64, location *time.Location) string {
+	return time.Unix(timestamp, 0).In(location).Format("2006-01-02 15:04:05 MST")
+}
+
 // Middleware to check cookie for web authentication
 func (r *BlueBerry) webAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -125,13 +161,33 @@ func (r *BlueBerry) showTask(c echo.Context) error {
 
 	totalPages := (totalTasks + tasksPerPage - 1) / tasksPerPage
 
+	// Schedules are only stored for registered tasks
+	task, _ := r.GetTask(taskName)
+
 	var templateSchedules []TemplateScheduleInfo
 	for _, schedule := range schedules {
 		templateSchedules = append(templateSchedules, TemplateScheduleInfo{
-			Schedule:               schedule.Schedule,
-			FormattedNextExecution: formatUnixTimestamp(schedule.NextExecution),
-			EntryID:                int(schedule.EntryID),
-			Params:                 schedule.Params,
+			ID:                          schedule.ID,
+			Schedule:                    schedule.Schedule,
+			Timezone:                    r.scheduleLocation(schedule).String(),
+			Overlap:                     task.overlapPolicy(schedule.ScheduleOptions),
+			Misfire:                     schedule.misfirePolicy(),
+			FormattedNextExecution:      formatUnixTimestamp(schedule.NextExecution),
+			FormattedNextExecutionLocal: formatUnixTimestampIn(schedule.NextExecution, r.scheduleLocation(schedule)),
+			Params:                      schedule.Params,
+			Paused:                      schedule.Paused,
+			Expired:                     schedule.Expired,
+			FormattedStartAt:            formatTimeIn(schedule.StartAt, r.scheduleLocation(schedule)),
+			FormattedEndAt:              formatTimeIn(schedule.EndAt, r.scheduleLocation(schedule)),
+			MaxOccurrences:              schedule.MaxOccurrences,
+			RemainingOccurrences:        schedule.RemainingOccurrences(),
+			Calendar:                    schedule.Calendar,
+			CalendarPolicy:              schedule.calendarPolicy(),
+			Jitter:                      schedule.Jitter,
+			Spread:                      schedule.Spread,
+			Timeout:                     schedule.Timeout,
+			Priority:                    schedule.Priority,
+			Origin:                      schedule.Origin,
 		})
 	}
 
@@ -142,6 +198,9 @@ func (r *BlueBerry) showTask(c echo.Context) error {
 			FormattedStartTime: formatTime(execution.StartTime),
 			FormattedEndTime:   formatTime(execution.EndTime),
 			Status:             execution.Status,
+			CatchUp:            execution.CatchUp,
+			Delayed:            !execution.RunAt.IsZero(),
+			Attempt:            execution.Attempt,
 			Params:             execution.Params,
 		})
 	}
@@ -210,28 +269,57 @@ func (r *BlueBerry) showExecution(c echo.Context) error {
 
 	totalPages := (totalLogs + size - 1) / size
 
+	// Retries are configured on the task, runs of tasks that are no longer registered show no limit
+	maxAttempts := 0
+	if task, ok := r.GetTask(execution.TaskName); ok {
+		maxAttempts = task.options.Retry.MaxAttempts
+	}
+
+	// Results are stored compactly, the page shows them indented
+	var formattedResult bytes.Buffer
+	if len(execution.Result) > 0 {
+		if err := json.Indent(&formattedResult, execution.Result, "", "  "); err != nil {
+			formattedResult.Write(execution.Result)
+		}
+	}
+
+	queuePosition := 0
+	if execution.Status == "queued" {
+		for _, queued := range r.GetQueue() {
+			if queued.ID == execution.ID {
+				queuePosition = queued.Position
+			}
+		}
+	}
+
 	data := struct {
 		TaskRun
-		Logs        []TaskRunLog
-		CurrentPage int
-		PageSize    int
-		TotalPages  int
-		HasPrevPage bool
-		HasNextPage bool
-		PrevPage    int
-		NextPage    int
-		Level       string
+		MaxAttempts   int
+		QueuePosition int
+		Result        string
+		Logs          []TaskRunLog
+		CurrentPage   int
+		PageSize      int
+		TotalPages    int
+		HasPrevPage   bool
+		HasNextPage   bool
+		PrevPage      int
+		NextPage      int
+		Level         string
 	}{
-		TaskRun:     execution,
-		Logs:        logs,
-		CurrentPage: page,
-		PageSize:    size,
-		TotalPages:  totalPages,
-		HasPrevPage: page > 1,
-		HasNextPage: page < totalPages,
-		PrevPage:    page - 1,
-		NextPage:    page + 1,
-		Level:       levelParam,
+		TaskRun:       execution,
+		MaxAttempts:   maxAttempts,
+		QueuePosition: queuePosition,
+		Result:        formattedResult.String(),
+		Logs:          logs,
+		CurrentPage:   page,
+		PageSize:      size,
+		TotalPages:    totalPages,
+		HasPrevPage:   page > 1,
+		HasNextPage:   page < totalPages,
+		PrevPage:      page - 1,
+		NextPage:      page + 1,
+		Level:         levelParam,
 	}
 
 	// Check if the request is from HTMX
@@ -318,9 +406,14 @@ func (r *BlueBerry) executeTaskForm(c echo.Context) error {
 	data := struct {
 		TaskName string
 		Schema   TaskSchema
+		Values   map[string]any
+		Timeout  time.Duration
+		Priority int
 	}{
 		TaskName: task.name,
 		Schema:   task.schema,
+		Timeout:  task.options.Timeout,
+		Priority: task.options.Priority,
 	}
 
 	return c.Render(http.StatusOK, "task_run.goml", data)
@@ -336,35 +429,369 @@ func (r *BlueBerry) handleExecuteTask(c echo.Context) error {
 
 	task := taskInterface.(*Task)
 
+	params, err := paramsFromForm(c, task.schema)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	timeout, err := parseFormDuration(c.FormValue("timeout"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid value for timeout, expected a duration such as 30s or 5m")
+	}
+
+	var priority *int
+	if value := strings.TrimSpace(c.FormValue("priority")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "Invalid value for priority")
+		}
+		priority = &parsed
+	}
+
+	taskID, err := task.ExecuteNowWithOptions(params, ExecuteOptions{Timeout: timeout, Priority: priority})
+
+	if errors.Is(err, ErrAlreadyRunning) {
+		return c.JSON(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.Redirect(http.StatusFound, fmt.Sprintf("%s/execution/%d", r.webPath, taskID))
+}
+
+// paramsFromForm reads the task parameters defined by the schema from a submitted form
+func paramsFromForm(c echo.Context, schema TaskSchema) (TaskParams, error) {
 	params := make(TaskParams)
-	for key := range task.schema.Fields {
+	for key, fieldType := range schema.Fields {
 		value := c.FormValue(key)
-		if task.schema.Fields[key] == TypeInt {
+		switch fieldType {
+		case TypeInt:
 			intVal, err := strconv.Atoi(value)
 			if err != nil {
-				return c.JSON(http.StatusBadRequest, fmt.Sprintf("Invalid value for %s", key))
+				return nil, fmt.Errorf("Invalid value for %s", key)
 			}
 			params[key] = intVal
-		} else if task.schema.Fields[key] == TypeFloat {
+		case TypeFloat:
 			floatVal, err := strconv.ParseFloat(value, 64)
 			if err != nil {
-				return c.JSON(http.StatusBadRequest, fmt.Sprintf("Invalid value for %s", key))
+				return nil, fmt.Errorf("Invalid value for %s", key)
 			}
 			params[key] = floatVal
-		} else if task.schema.Fields[key] == TypeBool {
-			boolVal := value == "on"
-			params[key] = boolVal
-			fmt.Printf("Parameter %s: %v\n", key, boolVal)
-		} else {
+		case TypeBool:
+			params[key] = value == "true" || value == "on"
+		default:
 			params[key] = value
 		}
 	}
+	return params, nil
+}
+
+// scheduleFormData is used for rendering the schedule create and edit form
+type scheduleFormData struct {
+	TaskName     string
+	ScheduleID   int
+	Schedule     string
+	CronFormat   string
+	Options      ScheduleOptions
+	StartAt      string // Validity window in the datetime-local format, in the zone of the schedule
+	EndAt        string
+	Calendars    []Calendar
+	Schema       TaskSchema
+	Values       map[string]any
+	ErrorMessage string
+}
+
+// newScheduleForm renders the form for creating a schedule
+func (r *BlueBerry) newScheduleForm(c echo.Context) error {
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, "Task not found")
+	}
+
+	return c.Render(http.StatusOK, "schedule_form.goml", scheduleFormData{
+		TaskName:   task.name,
+		CronFormat: r.CronFormat(),
+		Calendars:  r.listCalendars(),
+		Schema:     task.schema,
+	})
+}
 
-	taskID, err := task.ExecuteNow(params)
+// editScheduleForm renders the form for editing a schedule, prefilled with its current values
+func (r *BlueBerry) editScheduleForm(c echo.Context) error {
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, "Task not found")
+	}
 
+	scheduleID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid schedule ID"})
+	}
+
+	schedule, err := task.GetSchedule(scheduleID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	location := r.scheduleLocation(schedule)
+	return c.Render(http.StatusOK, "schedule_form.goml", scheduleFormData{
+		TaskName:   task.name,
+		ScheduleID: schedule.ID,
+		Schedule:   schedule.Schedule,
+		CronFormat: r.CronFormat(),
+		Options:    schedule.ScheduleOptions,
+		StartAt:    formatFormTime(schedule.StartAt, location),
+		EndAt:      formatFormTime(schedule.EndAt, location),
+		Calendars:  r.listCalendars(),
+		Schema:     task.schema,
+		Values:     schedule.Params,
+	})
+}
+
+// handleCreateSchedule processes the form submission to create a schedule
+func (r *BlueBerry) handleCreateSchedule(c echo.Context) error {
+	return r.saveScheduleFromForm(c, 0)
+}
+
+// handleUpdateSchedule processes the form submission to update a schedule
+func (r *BlueBerry) handleUpdateSchedule(c echo.Context) error {
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid schedule ID"})
+	}
+	return r.saveScheduleFromForm(c, scheduleID)
+}
+
+// saveScheduleFromForm creates (scheduleID 0) or updates a schedule, rendering validation errors inline
+func (r *BlueBerry) saveScheduleFromForm(c echo.Context, scheduleID int) error {
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, "Task not found")
+	}
+
+	options, optionsErr := r.scheduleOptionsFromForm(c)
+	data := scheduleFormData{
+		TaskName:   task.name,
+		ScheduleID: scheduleID,
+		Schedule:   c.FormValue("schedule"),
+		CronFormat: r.CronFormat(),
+		Options:    options,
+		StartAt:    c.FormValue("start_at"),
+		EndAt:      c.FormValue("end_at"),
+		Calendars:  r.listCalendars(),
+		Schema:     task.schema,
+		Values:     make(map[string]any),
+	}
+	for key := range task.schema.Fields {
+		data.Values[key] = c.FormValue(key)
+	}
+
+	params, err := paramsFromForm(c, task.schema)
+	if err == nil {
+		err = optionsErr
+	}
+	if err == nil {
+		err = task.ValidateParams(params)
+	}
+	if err == nil {
+		if scheduleID == 0 {
+			_, err = task.CreateScheduleWithOptions(params, data.Schedule, data.Options)
+		} else {
+			_, err = task.UpdateScheduleWithOptions(scheduleID, params, data.Schedule, data.Options)
+		}
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrScheduleNotFound) {
+			status = http.StatusNotFound
+		}
+		data.ErrorMessage = err.Error()
+		return c.Render(status, "schedule_form.goml", data)
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/task/%s", r.webPath, task.name))
+}
+
+// scheduleOptionsFromForm reads the optional schedule settings from the schedule form
+func (r *BlueBerry) scheduleOptionsFromForm(c echo.Context) (ScheduleOptions, error) {
+	opts := ScheduleOptions{
+		Timezone: strings.TrimSpace(c.FormValue("timezone")),
+		Overlap:  OverlapPolicy(c.FormValue("overlap")),
+		Misfire:  MisfirePolicy(c.FormValue("misfire")),
+
+		Calendar:       c.FormValue("calendar"),
+		CalendarPolicy: CalendarPolicy(c.FormValue("calendar_policy")),
+	}
+
+	if limit := strings.TrimSpace(c.FormValue("misfire_limit")); limit != "" {
+		misfireLimit, err := strconv.Atoi(limit)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid value for misfire limit")
+		}
+		opts.MisfireLimit = misfireLimit
+	}
+
+	if occurrences := strings.TrimSpace(c.FormValue("max_occurrences")); occurrences != "" {
+		maxOccurrences, err := strconv.Atoi(occurrences)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid value for max occurrences")
+		}
+		opts.MaxOccurrences = maxOccurrences
+	}
+
+	if value := strings.TrimSpace(c.FormValue("priority")); value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid value for priority")
+		}
+		opts.Priority = &priority
+	}
+
+	var err error
+	if opts.Jitter, err = parseFormDuration(c.FormValue("jitter")); err != nil {
+		return opts, fmt.Errorf("Invalid value for jitter, expected a duration such as 30s or 5m")
+	}
+	if opts.Spread, err = parseFormDuration(c.FormValue("spread")); err != nil {
+		return opts, fmt.Errorf("Invalid value for spread, expected a duration such as 30s or 5m")
+	}
+	if opts.Timeout, err = parseFormDuration(c.FormValue("timeout")); err != nil {
+		return opts, fmt.Errorf("Invalid value for timeout, expected a duration such as 30s or 5m")
+	}
+
+	// The validity window is entered in the zone the schedule is evaluated in
+	location := r.location
+	if opts.Timezone != "" {
+		if loaded, err := time.LoadLocation(opts.Timezone); err == nil {
+			location = loaded
+		}
+	}
+	if opts.StartAt, err = parseFormTime(c.FormValue("start_at"), location); err != nil {
+		return opts, fmt.Errorf("Invalid value for start time")
+	}
+	if opts.EndAt, err = parseFormTime(c.FormValue("end_at"), location); err != nil {
+		return opts, fmt.Errorf("Invalid value for end time")
+	}
+	return opts, nil
+}
+
+// parseFormDuration parses a duration form input such as "30s", empty values give 0
+func parseFormDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// formTimeLayout is the value format of datetime-local form inputs
+const formTimeLayout = "2006-01-02T15:04"
+
+// parseFormTime parses the value of a datetime-local form input, empty values give the zero time
+func parseFormTime(value string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(formTimeLayout, value, location)
+}
+
+// formatFormTime formats a time for a datetime-local form input, zero times are left empty
+func formatFormTime(t time.Time, location *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(location).Format(formTimeLayout)
+}
+
+// schedulePreviewData is used for rendering the live preview of the schedule form
+type schedulePreviewData struct {
+	Description  string
+	Timezone     string
+	Next         []string
+	ErrorMessage string
+}
+
+// showQueue renders the runs waiting for a slot in the order they start in
+func (r *BlueBerry) showQueue(c echo.Context) error {
+	data := struct {
+		Runs              []QueuedRun
+		MaxConcurrentRuns int
+	}{
+		Runs:              r.GetQueue(),
+		MaxConcurrentRuns: r.options.MaxConcurrentRuns,
+	}
+	return c.Render(http.StatusOK, "queue.goml", data)
+}
+
+// showSchedulePreview renders the next firing times of the cron expression entered in the schedule form
+func (r *BlueBerry) showSchedulePreview(c echo.Context) error {
+	schedule := strings.TrimSpace(c.QueryParam("schedule"))
+	if schedule == "" {
+		return c.Render(http.StatusOK, "schedule_preview.goml", schedulePreviewData{})
+	}
+
+	// Errors are rendered with a 200 status so that htmx swaps them into the form
+	preview, err := r.PreviewSchedule(schedule, 5, strings.TrimSpace(c.QueryParam("timezone")))
+	if err != nil {
+		return c.Render(http.StatusOK, "schedule_preview.goml", schedulePreviewData{ErrorMessage: err.Error()})
+	}
+
+	data := schedulePreviewData{Description: preview.Description, Timezone: preview.Timezone}
+	for _, next := range preview.Next {
+		data.Next = append(data.Next, next.Format("Mon 2006-01-02 15:04:05 MST"))
+	}
+	return c.Render(http.StatusOK, "schedule_preview.goml", data)
+}
+
+// handleDeleteSchedule processes the form submission to delete a schedule
+func (r *BlueBerry) handleDeleteSchedule(c echo.Context) error {
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, "Task not found")
+	}
+
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid schedule ID"})
+	}
+
+	if err := task.DeleteSchedule(scheduleID); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/task/%s", r.webPath, task.name))
+}
+
+// handlePauseSchedule processes the form submission to pause a schedule
+func (r *BlueBerry) handlePauseSchedule(c echo.Context) error {
+	return r.setSchedulePausedFromForm(c, true)
+}
+
+// handleResumeSchedule processes the form submission to resume a schedule
+func (r *BlueBerry) handleResumeSchedule(c echo.Context) error {
+	return r.setSchedulePausedFromForm(c, false)
+}
+
+func (r *BlueBerry) setSchedulePausedFromForm(c echo.Context, paused bool) error {
+	task, ok := r.GetTask(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, "Task not found")
+	}
+
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid schedule ID"})
+	}
+
+	if paused {
+		_, err = task.PauseSchedule(scheduleID)
+	} else {
+		_, err = task.ResumeSchedule(scheduleID)
+	}
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
 	}
 
-	return c.Redirect(http.StatusFound, fmt.Sprintf("/execution/%d", taskID))
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/task/%s", r.webPath, task.name))
 }
@@ -32,20 +32,554 @@ const docTemplate = `{
                 }
             }
         },
+        "/calendars": {
+            "get": {
+                "description": "Get all registered calendars with their excluded dates, weekdays and blackout windows",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Calendars"
+                ],
+                "summary": "Get calendars",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/blueberry.Calendar"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/execution/{id}": {
+            "get": {
+                "description": "Get the status, parameters and result of a task execution",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Executions"
+                ],
+                "summary": "Get a task execution by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task Execution ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.TaskExecution"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid execution ID",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Execution not found",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/execution/{id}/cancel": {
             "post": {
-                "description": "Cancel a specific task execution by its ID",
+                "description": "Cancel a running, pending or queued task execution by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Executions"
+                ],
+                "summary": "Cancel a specific task execution by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task Execution ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/maintenance": {
+            "get": {
+                "description": "While maintenance mode is enabled scheduled triggers are skipped and recorded as skipped runs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Maintenance"
+                ],
+                "summary": "Get maintenance mode",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.MaintenanceMode"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Suspend or restore all scheduled triggers. Manual executions are still allowed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Maintenance"
+                ],
+                "summary": "Set maintenance mode",
+                "parameters": [
+                    {
+                        "description": "Maintenance mode",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.MaintenanceMode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.MaintenanceMode"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/queue": {
+            "get": {
+                "description": "Get the queued runs in the order they start in, runs with a higher priority first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Executions"
+                ],
+                "summary": "Get the run queue",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/blueberry.QueuedRun"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/schedule/preview": {
+            "get": {
+                "description": "Get the next firing times and a human-readable description of a cron expression",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Preview a cron expression",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cron expression",
+                        "name": "schedule",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone, the server default when empty",
+                        "name": "timezone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of firing times (1-100, default 5)",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.SchedulePreview"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid cron expression, time zone or count",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{name}/execute": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Execute a specified task by its name with the provided parameters.\nRepeated requests with the same idempotency key return the execution ID of the first one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Execute a task by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key of at most 255 characters, alternative to the idempotency_key field",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Task Parameters",
+                        "name": "params",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ExecuteTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task executed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.GenericResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A run with the same parameters is already queued or running",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{name}/executions": {
+            "get": {
+                "description": "Get all executions for a specific task by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Executions"
+                ],
+                "summary": "Get all executions for a specific task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/blueberry.getTaskExecutionsResponse"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{name}/schedules": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Register a new schedule for a task with the given parameters and cron expression",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Create a schedule for a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Schedule",
+                        "name": "schedule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ScheduleInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters or cron expression",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/task/{name}/schedules/{id}": {
+            "get": {
+                "description": "Get a schedule of a task by its stable schedule ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Get a schedule by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ScheduleInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid schedule ID",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task or schedule not found",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replace the parameters, cron expression and options of a schedule, keeping its schedule ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Update a schedule of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Schedule",
+                        "name": "schedule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ScheduleInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters or cron expression",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task or schedule not found",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove a schedule of a task by its schedule ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Executions"
+                    "Schedules"
                 ],
-                "summary": "Cancel a specific task execution by ID",
+                "summary": "Delete a schedule of a task",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "integer",
-                        "description": "Task Execution ID",
+                        "description": "Schedule ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -53,41 +587,47 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Schedule deleted successfully",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/blueberry.GenericResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid schedule ID",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Task or schedule not found",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/task/{name}/execute": {
+        "/task/{name}/schedules/{id}/pause": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Execute a specified task by its name with the provided parameters",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Stop a schedule from triggering while keeping its parameters and cron expression",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Execute a task by name",
+                "tags": [
+                    "Schedules"
+                ],
+                "summary": "Pause a schedule of a task",
                 "parameters": [
                     {
                         "type": "string",
@@ -97,53 +637,56 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Task Parameters",
-                        "name": "params",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/rasberry.ExecuteTaskRequest"
-                        }
+                        "type": "integer",
+                        "description": "Schedule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Task executed successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rasberry.GenericResponse"
+                            "$ref": "#/definitions/blueberry.ScheduleInfo"
                         }
                     },
                     "400": {
-                        "description": "Invalid parameters",
+                        "description": "Invalid schedule ID",
                         "schema": {
-                            "$ref": "#/definitions/rasberry.ErrorResponse"
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Task not found",
+                        "description": "Task or schedule not found",
                         "schema": {
-                            "$ref": "#/definitions/rasberry.ErrorResponse"
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/rasberry.ErrorResponse"
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/task/{name}/executions": {
-            "get": {
-                "description": "Get all executions for a specific task by name",
+        "/task/{name}/schedules/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Resume triggering a paused schedule",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Executions"
+                    "Schedules"
                 ],
-                "summary": "Get all executions for a specific task",
+                "summary": "Resume a schedule of a task",
                 "parameters": [
                     {
                         "type": "string",
@@ -151,16 +694,38 @@ const docTemplate = `{
                         "name": "name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/rasberry.getTaskExecutionsResponse"
-                            }
+                            "$ref": "#/definitions/blueberry.ScheduleInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid schedule ID",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task or schedule not found",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/blueberry.ErrorResponse"
                         }
                     }
                 }
@@ -219,7 +784,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/rasberry.getTaskRunLogResponse"
+                                "$ref": "#/definitions/blueberry.getTaskRunLogResponse"
                             }
                         }
                     }
@@ -242,7 +807,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/rasberry.TaskInfo"
+                                "$ref": "#/definitions/blueberry.TaskInfo"
                             }
                         }
                     }
@@ -251,7 +816,62 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "rasberry.ErrorResponse": {
+        "blueberry.Blackout": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "blueberry.Calendar": {
+            "type": "object",
+            "properties": {
+                "blackouts": {
+                    "description": "Blackouts are times of day excluded on every day",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blueberry.Blackout"
+                    }
+                },
+                "dates": {
+                    "description": "Dates are excluded days in the \"2006-01-02\" format, e.g. bank holidays",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "weekdays": {
+                    "description": "Weekdays are excluded every week, e.g. time.Saturday and time.Sunday",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "blueberry.CalendarPolicy": {
+            "type": "string",
+            "enum": [
+                "skip",
+                "defer"
+            ],
+            "x-enum-comments": {
+                "CalendarDefer": "Start a delayed run at the next time the calendar allows",
+                "CalendarSkip": "Do not start a run, it is recorded as skipped (default)"
+            },
+            "x-enum-varnames": [
+                "CalendarSkip",
+                "CalendarDefer"
+            ]
+        },
+        "blueberry.ErrorResponse": {
             "type": "object",
             "properties": {
                 "reason": {
@@ -262,36 +882,324 @@ const docTemplate = `{
                 }
             }
         },
-        "rasberry.ExecuteTaskRequest": {
+        "blueberry.ExecuteTaskRequest": {
             "type": "object",
             "properties": {
+                "idempotency_key": {
+                    "description": "IdempotencyKey deduplicates retried requests, the Idempotency-Key header can be used instead. At most 255 characters.",
+                    "type": "string"
+                },
                 "params": {
-                    "$ref": "#/definitions/rasberry.TaskParams"
+                    "$ref": "#/definitions/blueberry.TaskParams"
+                },
+                "priority": {
+                    "description": "Priority overrides the priority of the task for this run when set, 0 included",
+                    "type": "integer"
+                },
+                "timeout": {
+                    "description": "Timeout in nanoseconds, overrides the timeout of the task for this run",
+                    "type": "integer"
                 }
             }
         },
-        "rasberry.GenericResponse": {
+        "blueberry.GenericResponse": {
             "type": "object",
             "additionalProperties": true
         },
-        "rasberry.ScheduleInfo": {
+        "blueberry.MaintenanceMode": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "blueberry.MisfirePolicy": {
+            "type": "string",
+            "enum": [
+                "ignore",
+                "fire_once",
+                "fire_all"
+            ],
+            "x-enum-comments": {
+                "MisfireFireAll": "Start a catch-up run per missed trigger, up to MisfireLimit",
+                "MisfireFireOnce": "Start a single catch-up run for all missed triggers",
+                "MisfireIgnore": "Drop the missed triggers (default)"
+            },
+            "x-enum-varnames": [
+                "MisfireIgnore",
+                "MisfireFireOnce",
+                "MisfireFireAll"
+            ]
+        },
+        "blueberry.OverlapPolicy": {
+            "type": "string",
+            "enum": [
+                "allow",
+                "skip",
+                "queue",
+                "replace"
+            ],
+            "x-enum-comments": {
+                "OverlapAllow": "Start another run next to the running one (default)",
+                "OverlapQueue": "Start the run once the previous runs have finished",
+                "OverlapReplace": "Cancel the running run and start a new one",
+                "OverlapSkip": "Do not start a run, it is recorded as skipped"
+            },
+            "x-enum-varnames": [
+                "OverlapAllow",
+                "OverlapSkip",
+                "OverlapQueue",
+                "OverlapReplace"
+            ]
+        },
+        "blueberry.QueuedRun": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "queued_at": {
+                    "type": "string"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "task_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "blueberry.ScheduleInfo": {
             "type": "object",
             "properties": {
+                "calendar": {
+                    "description": "Calendar is the name of a registered calendar excluding days and times the schedule must not run at",
+                    "type": "string"
+                },
+                "calendar_policy": {
+                    "description": "CalendarPolicy decides whether triggers excluded by the calendar are skipped or deferred. Defaults to CalendarSkip.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/blueberry.CalendarPolicy"
+                        }
+                    ]
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "expired": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "jitter": {
+                    "description": "Jitter delays every trigger by a random duration up to this value",
+                    "type": "integer"
+                },
+                "last_fired_ts": {
+                    "type": "integer"
+                },
+                "max_occurrences": {
+                    "description": "MaxOccurrences expires the schedule after it started this many runs. Triggers that do not start, queue or defer\na run, e.g. because they were skipped, are not counted. 0 means no limit.",
+                    "type": "integer"
+                },
+                "misfire": {
+                    "description": "Misfire decides whether triggers missed while the process was down are caught up on start.\nDefaults to MisfireIgnore.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/blueberry.MisfirePolicy"
+                        }
+                    ]
+                },
+                "misfire_limit": {
+                    "description": "MisfireLimit caps the number of catch-up runs started by MisfireFireAll, only the most recent\nmissed triggers are kept. It is required with MisfireFireAll.",
+                    "type": "integer"
+                },
+                "next_execution_local": {
+                    "type": "string"
+                },
                 "next_execution_ts": {
                     "type": "integer"
                 },
+                "occurrences": {
+                    "type": "integer"
+                },
+                "origin": {
+                    "$ref": "#/definitions/blueberry.ScheduleOrigin"
+                },
+                "overlap": {
+                    "description": "Overlap overrides the overlap policy of the task for this schedule",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/blueberry.OverlapPolicy"
+                        }
+                    ]
+                },
                 "params": {
                     "type": "object",
                     "additionalProperties": true
                 },
+                "paused": {
+                    "type": "boolean"
+                },
+                "priority": {
+                    "description": "Priority overrides the priority of the task for runs started by this schedule when set, 0 included. nil\nkeeps the priority of the task.",
+                    "type": "integer"
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "spread": {
+                    "description": "Spread delays every trigger by a fixed offset up to this value, derived from a hash of the schedule. Schedules\nsharing a cron expression are spread across the window while each keeps a steady interval.",
+                    "type": "integer"
+                },
+                "start_at": {
+                    "description": "StartAt and EndAt limit the time the schedule triggers in, zero values leave that side open",
+                    "type": "string"
+                },
+                "task_name": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "description": "Timeout overrides the timeout of the task for runs started by this schedule",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "description": "Timezone is the IANA name of the zone the cron expression is evaluated in, e.g. \"Europe/Berlin\".\nWhen empty the default location of the BlueBerry instance is used.",
+                    "type": "string"
+                }
+            }
+        },
+        "blueberry.ScheduleOrigin": {
+            "type": "string",
+            "enum": [
+                "code",
+                "api"
+            ],
+            "x-enum-comments": {
+                "OriginAPI": "Created at runtime through the API, the web UI, CreateSchedule or RegisterSchedule after InitTaskScheduler",
+                "OriginCode": "Registered with RegisterSchedule before InitTaskScheduler, again on every start"
+            },
+            "x-enum-varnames": [
+                "OriginCode",
+                "OriginAPI"
+            ]
+        },
+        "blueberry.SchedulePreview": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "next": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "blueberry.ScheduleRequest": {
+            "type": "object",
+            "properties": {
+                "calendar": {
+                    "description": "Calendar is the name of a registered calendar excluding days and times the schedule must not run at",
+                    "type": "string"
+                },
+                "calendar_policy": {
+                    "description": "CalendarPolicy decides whether triggers excluded by the calendar are skipped or deferred. Defaults to CalendarSkip.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/blueberry.CalendarPolicy"
+                        }
+                    ]
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "jitter": {
+                    "description": "Jitter delays every trigger by a random duration up to this value",
+                    "type": "integer"
+                },
+                "max_occurrences": {
+                    "description": "MaxOccurrences expires the schedule after it started this many runs. Triggers that do not start, queue or defer\na run, e.g. because they were skipped, are not counted. 0 means no limit.",
+                    "type": "integer"
+                },
+                "misfire": {
+                    "description": "Misfire decides whether triggers missed while the process was down are caught up on start.\nDefaults to MisfireIgnore.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/blueberry.MisfirePolicy"
+                        }
+                    ]
+                },
+                "misfire_limit": {
+                    "description": "MisfireLimit caps the number of catch-up runs started by MisfireFireAll, only the most recent\nmissed triggers are kept. It is required with MisfireFireAll.",
+                    "type": "integer"
+                },
+                "overlap": {
+                    "description": "Overlap overrides the overlap policy of the task for this schedule",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/blueberry.OverlapPolicy"
+                        }
+                    ]
+                },
+                "params": {
+                    "$ref": "#/definitions/blueberry.TaskParams"
+                },
+                "priority": {
+                    "description": "Priority overrides the priority of the task for runs started by this schedule when set, 0 included. nil\nkeeps the priority of the task.",
+                    "type": "integer"
+                },
                 "schedule": {
                     "type": "string"
+                },
+                "spread": {
+                    "description": "Spread delays every trigger by a fixed offset up to this value, derived from a hash of the schedule. Schedules\nsharing a cron expression are spread across the window while each keeps a steady interval.",
+                    "type": "integer"
+                },
+                "start_at": {
+                    "description": "StartAt and EndAt limit the time the schedule triggers in, zero values leave that side open",
+                    "type": "string"
+                },
+                "timeout": {
+                    "description": "Timeout overrides the timeout of the task for runs started by this schedule",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "description": "Timezone is the IANA name of the zone the cron expression is evaluated in, e.g. \"Europe/Berlin\".\nWhen empty the default location of the BlueBerry instance is used.",
+                    "type": "string"
                 }
             }
         },
-        "rasberry.TaskExecution": {
+        "blueberry.TaskExecution": {
             "type": "object",
             "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "catch_up": {
+                    "type": "boolean"
+                },
                 "duration": {
                     "type": "string"
                 },
@@ -301,10 +1209,28 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "idempotency_key": {
+                    "type": "string"
+                },
                 "params": {
                     "type": "object",
                     "additionalProperties": true
                 },
+                "priority": {
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "retry_of": {
+                    "type": "integer"
+                },
+                "run_at": {
+                    "type": "string"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
                 "start_time": {
                     "type": "string"
                 },
@@ -313,16 +1239,19 @@ const docTemplate = `{
                 },
                 "task_name": {
                     "type": "string"
+                },
+                "timeout": {
+                    "type": "integer"
                 }
             }
         },
-        "rasberry.TaskInfo": {
+        "blueberry.TaskInfo": {
             "type": "object",
             "properties": {
                 "schedules": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/rasberry.ScheduleInfo"
+                        "$ref": "#/definitions/blueberry.ScheduleInfo"
                     }
                 },
                 "task_name": {
@@ -330,11 +1259,11 @@ const docTemplate = `{
                 }
             }
         },
-        "rasberry.TaskParams": {
+        "blueberry.TaskParams": {
             "type": "object",
             "additionalProperties": true
         },
-        "rasberry.TaskRunLog": {
+        "blueberry.TaskRunLog": {
             "type": "object",
             "properties": {
                 "id": {
@@ -354,24 +1283,24 @@ const docTemplate = `{
                 }
             }
         },
-        "rasberry.getTaskExecutionsResponse": {
+        "blueberry.getTaskExecutionsResponse": {
             "type": "object",
             "properties": {
                 "task_executions": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/rasberry.TaskExecution"
+                        "$ref": "#/definitions/blueberry.TaskExecution"
                     }
                 }
             }
         },
-        "rasberry.getTaskRunLogResponse": {
+        "blueberry.getTaskRunLogResponse": {
             "type": "object",
             "properties": {
                 "logs": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/rasberry.TaskRunLog"
+                        "$ref": "#/definitions/blueberry.TaskRunLog"
                     }
                 }
             }
@@ -14,7 +14,6 @@ import (
 	blueberry "github.com/ersauravadhikari/blueberry-go/blueberry"
 	"github.com/ersauravadhikari/blueberry-go/blueberry/store"
 	"github.com/labstack/echo/v4"
-	"github.com/robfig/cron/v3"
 )
 
 // Schedule request/response structures
@@ -25,8 +24,8 @@ type ScheduleRequest struct {
 }
 
 type ScheduleResponse struct {
-	EntryID int    `json:"entry_id"`
-	Message string `json:"message"`
+	ScheduleID int    `json:"schedule_id"`
+	Message    string `json:"message"`
 }
 
 type UpdateScheduleRequest struct {
@@ -69,25 +68,25 @@ func (h *ScheduleHandler) CreateSchedule(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
 	}
 
-	schedule, err := task.RegisterSchedule(req.Params, req.Schedule)
+	schedule, err := task.CreateSchedule(req.Params, req.Schedule)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
 	return c.JSON(http.StatusCreated, ScheduleResponse{
-		EntryID: int(schedule.EntryID),
-		Message: "Schedule created successfully",
+		ScheduleID: schedule.ID,
+		Message:    "Schedule created successfully",
 	})
 }
 
 // Handler to update schedule
 func (h *ScheduleHandler) UpdateSchedule(c echo.Context) error {
 	taskName := c.Param("taskName")
-	entryIDStr := c.Param("entryID")
+	scheduleIDStr := c.Param("scheduleID")
 
-	entryID, err := strconv.Atoi(entryIDStr)
+	scheduleID, err := strconv.Atoi(scheduleIDStr)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid entry ID"})
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid schedule ID"})
 	}
 
 	task, exists := h.tasks[taskName]
@@ -100,29 +99,26 @@ func (h *ScheduleHandler) UpdateSchedule(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	// Delete existing schedule
-	task.DeleteSchedule(cron.EntryID(entryID))
-
-	// Create new schedule
-	schedule, err := task.RegisterSchedule(req.Params, req.Schedule)
+	// Update the schedule in place, keeping its schedule ID
+	schedule, err := task.UpdateSchedule(scheduleID, req.Params, req.Schedule)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
 	return c.JSON(http.StatusOK, ScheduleResponse{
-		EntryID: int(schedule.EntryID),
-		Message: "Schedule updated successfully",
+		ScheduleID: schedule.ID,
+		Message:    "Schedule updated successfully",
 	})
 }
 
 // Handler to delete schedule
 func (h *ScheduleHandler) DeleteSchedule(c echo.Context) error {
 	taskName := c.Param("taskName")
-	entryIDStr := c.Param("entryID")
+	scheduleIDStr := c.Param("scheduleID")
 
-	entryID, err := strconv.Atoi(entryIDStr)
+	scheduleID, err := strconv.Atoi(scheduleIDStr)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid entry ID"})
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid schedule ID"})
 	}
 
 	task, exists := h.tasks[taskName]
@@ -130,7 +126,9 @@ func (h *ScheduleHandler) DeleteSchedule(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
 	}
 
-	task.DeleteSchedule(cron.EntryID(entryID))
+	if err := task.DeleteSchedule(scheduleID); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
 	return c.JSON(http.StatusOK, map[string]string{"message": "Schedule deleted successfully"})
 }
 
@@ -171,8 +169,8 @@ func main() {
 	// Register custom routes
 	customAPI := e.Group("/api/v1/schedules")
 	customAPI.POST("", scheduleHandler.CreateSchedule)
-	customAPI.PUT("/:taskName/:entryID", scheduleHandler.UpdateSchedule)
-	customAPI.DELETE("/:taskName/:entryID", scheduleHandler.DeleteSchedule)
+	customAPI.PUT("/:taskName/:scheduleID", scheduleHandler.UpdateSchedule)
+	customAPI.DELETE("/:taskName/:scheduleID", scheduleHandler.DeleteSchedule)
 
 	// Handle system signals
 	sigChan := make(chan os.Signal, 1)
@@ -185,6 +183,8 @@ func main() {
 		os.Exit(0)
 	}()
 
-	bb.InitTaskScheduler()
+	if err := bb.InitTaskScheduler(); err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
 	e.Start(":8080")
 }
@@ -73,7 +73,7 @@ func main() {
 	}
 
 	// schedule var contains the schedule information
-	fmt.Printf("Schedule with ID: %v has been registerd with CRON %s", sc.EntryID, sc.Schedule)
+	fmt.Printf("Schedule with ID: %v has been registerd with CRON %s", sc.ID, sc.Schedule)
 
 	_, err = tsk1.ExecuteNow(rasberry.TaskParams{
 		"param1": "value1",
@@ -96,6 +96,8 @@ func main() {
 		os.Exit(0)
 	}()
 
-	rb.InitTaskScheduler()
+	if err := rb.InitTaskScheduler(); err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
 	rb.RunAPI("8080")
 }
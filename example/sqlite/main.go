@@ -93,7 +93,7 @@ func main() {
 	}
 
 	// schedule var contains the schedule information
-	fmt.Printf("Schedule with ID: %v has been registerd with CRON %s", sc.EntryID, sc.Schedule)
+	fmt.Printf("Schedule with ID: %v has been registerd with CRON %s", sc.ID, sc.Schedule)
 
 	_, err = tsk1.ExecuteNow(rasberry.TaskParams{
 		"param1": "value1",
@@ -105,7 +105,9 @@ func main() {
 	}
 
 	// You can also remove the schedule dynamically.
-	tsk1.DeleteSchedule(sc.EntryID) // Remove the registered schedule
+	if err := tsk1.DeleteSchedule(sc.ID); err != nil { // Remove the registered schedule
+		log.Fatalf("Unable to delete schedule: %v", err)
+	}
 
 	// Handle system signals for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -119,6 +121,8 @@ func main() {
 		os.Exit(0)
 	}()
 
-	rb.InitTaskScheduler()
+	if err := rb.InitTaskScheduler(); err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
 	rb.RunAPI("8080")
 }